@@ -1,12 +1,20 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tidwall/gjson"
 
 	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg/ioctl"
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg/logpage"
 	"github.com/E4-Computer-Engineering/nvme_exporter/pkg/utils"
 )
 
@@ -31,6 +39,273 @@ func getOcpSmartLogData(devicePath string) gjson.Result {
 	return ocpSmartLog
 }
 
+func getOcpLatencyMonitorLogData(devicePath string) gjson.Result {
+	latencyLog, err := utils.ExecuteJSONCommand("nvme", "ocp", "latency-monitor-log", devicePath, "-o", "json")
+	if err != nil {
+		log.Printf("OCP latency monitor log not supported or error running latency-monitor-log %s -o json: %s\n",
+			devicePath, err)
+
+		return gjson.Result{}
+	}
+
+	return latencyLog
+}
+
+func getOcpErrorRecoveryLogData(devicePath string) gjson.Result {
+	errorRecoveryLog, err := utils.ExecuteJSONCommand("nvme", "ocp", "error-recovery-log", devicePath, "-o", "json")
+	if err != nil {
+		log.Printf("OCP error recovery log not supported or error running error-recovery-log %s -o json: %s\n",
+			devicePath, err)
+
+		return gjson.Result{}
+	}
+
+	return errorRecoveryLog
+}
+
+func getOcpDeviceCapabilityLogData(devicePath string) gjson.Result {
+	capabilityLog, err := utils.ExecuteJSONCommand("nvme", "ocp", "device-capability-log", devicePath, "-o", "json")
+	if err != nil {
+		log.Printf("OCP device capabilities log not supported or error running device-capability-log %s -o json: %s\n",
+			devicePath, err)
+
+		return gjson.Result{}
+	}
+
+	return capabilityLog
+}
+
+func getOcpUnsupportedReqsLogData(devicePath string) gjson.Result {
+	unsupportedLog, err := utils.ExecuteJSONCommand("nvme", "ocp", "unsupported-reqs-log", devicePath, "-o", "json")
+	if err != nil {
+		log.Printf("OCP unsupported requirements log not supported or error running unsupported-reqs-log %s -o json: %s\n",
+			devicePath, err)
+
+		return gjson.Result{}
+	}
+
+	return unsupportedLog
+}
+
+func getOcpTelemetryStringLogData(devicePath string) gjson.Result {
+	stringLog, err := utils.ExecuteJSONCommand("nvme", "ocp", "telemetry-string-log", devicePath, "-o", "json")
+	if err != nil {
+		log.Printf("OCP telemetry string log not supported or error running telemetry-string-log %s -o json: %s\n",
+			devicePath, err)
+
+		return gjson.Result{}
+	}
+
+	return stringLog
+}
+
+func getOcpHardwareComponentLogData(devicePath string) gjson.Result {
+	componentLog, err := utils.ExecuteJSONCommand("nvme", "ocp", "hardware-component-log", devicePath, "-o", "json")
+	if err != nil {
+		log.Printf("OCP hardware component log not supported or error running hardware-component-log %s -o json: %s\n",
+			devicePath, err)
+
+		return gjson.Result{}
+	}
+
+	return componentLog
+}
+
+func getVendorLogData(command []string, devicePath string) gjson.Result {
+	args := append(slices.Clone(command), devicePath, "-o", "json")
+
+	vendorLog, err := utils.ExecuteJSONCommand("nvme", args...)
+	if err != nil {
+		log.Printf("Vendor plugin not supported or error running %s %s -o json: %s\n",
+			strings.Join(command, " "), devicePath, err)
+
+		return gjson.Result{}
+	}
+
+	return vendorLog
+}
+
+func getCustomCommandData(command []string, devicePath string) gjson.Result {
+	args := append(slices.Clone(command), devicePath, "-o", "json")
+
+	customLog, err := utils.ExecuteJSONCommand("nvme", args...)
+	if err != nil {
+		log.Printf("Error running custom collector command %s %s -o json: %s\n",
+			strings.Join(command, " "), devicePath, err)
+	}
+
+	return customLog
+}
+
+func getIdentifyControllerData(devicePath string) gjson.Result {
+	identifyController, err := utils.ExecuteJSONCommand("nvme", "id-ctrl", devicePath, "-o", "json")
+	if err != nil {
+		log.Printf("Error running id-ctrl %s -o json: %s\n", devicePath, err)
+	}
+
+	return identifyController
+}
+
+func getFeatureData(devicePath string, featureID int, cdw11 uint32) gjson.Result {
+	args := []string{"get-feature", devicePath, fmt.Sprintf("--feature-id=0x%02x", featureID)}
+
+	// Some features, such as the temperature threshold, select the reported value through CDW11
+	if cdw11 != 0 {
+		args = append(args, fmt.Sprintf("--cdw11=0x%x", cdw11))
+	}
+
+	feature, err := utils.ExecuteJSONCommand("nvme", append(args, "-o", "json")...)
+	if err != nil {
+		log.Printf("Error running %s -o json: %s\n", strings.Join(args, " "), err)
+	}
+
+	return feature
+}
+
+func getEnduranceLogData(devicePath string, groupID int64) gjson.Result {
+	enduranceLog, err := utils.ExecuteJSONCommand(
+		"nvme", "endurance-log", devicePath, "--group-id="+strconv.FormatInt(groupID, 10), "-o", "json",
+	)
+	if err != nil {
+		log.Printf("Error running endurance-log %s --group-id=%d -o json: %s\n", devicePath, groupID, err)
+	}
+
+	return enduranceLog
+}
+
+func getFdpLogData(subcommand string, devicePath string, groupID int64) gjson.Result {
+	fdpLog, err := utils.ExecuteJSONCommand(
+		"nvme", "fdp", subcommand, devicePath, "--endgrp-id="+strconv.FormatInt(groupID, 10), "-o", "json",
+	)
+	if err != nil {
+		log.Printf("Error running fdp %s %s --endgrp-id=%d -o json: %s\n", subcommand, devicePath, groupID, err)
+	}
+
+	return fdpLog
+}
+
+func getZnsIdentifyNamespaceData(devicePath string) gjson.Result {
+	identifyNamespace, err := utils.ExecuteJSONCommand("nvme", "zns", "id-ns", devicePath, "-o", "json")
+	if err != nil {
+		log.Printf("Error running zns id-ns %s -o json: %s\n", devicePath, err)
+	}
+
+	return identifyNamespace
+}
+
+func getZnsReportZonesData(devicePath string) gjson.Result {
+	zones, err := utils.ExecuteJSONCommand("nvme", "zns", "report-zones", devicePath, "-o", "json")
+	if err != nil {
+		log.Printf("Error running zns report-zones %s -o json: %s\n", devicePath, err)
+	}
+
+	return zones
+}
+
+func getPersistentEventLogData(devicePath string) gjson.Result {
+	// Action 1 establishes a new reporting context, so that the log includes the latest events
+	eventLog, err := utils.ExecuteJSONCommand("nvme", "persistent-event-log", devicePath, "--action=1", "-o", "json")
+	if err != nil {
+		log.Printf("Error running persistent-event-log %s --action=1 -o json: %s\n", devicePath, err)
+	}
+
+	return eventLog
+}
+
+func getSanitizeLogData(devicePath string) gjson.Result {
+	sanitizeLog, err := utils.ExecuteJSONCommand("nvme", "sanitize-log", devicePath, "-o", "json")
+	if err != nil {
+		log.Printf("Error running sanitize-log %s -o json: %s\n", devicePath, err)
+	}
+
+	return sanitizeLog
+}
+
+func getIdentifyNamespaceData(devicePath string) gjson.Result {
+	identifyNamespace, err := utils.ExecuteJSONCommand("nvme", "id-ns", devicePath, "-o", "json")
+	if err != nil {
+		log.Printf("Error running id-ns %s -o json: %s\n", devicePath, err)
+	}
+
+	return identifyNamespace
+}
+
+func getReservationReportData(devicePath string) gjson.Result {
+	reservationReport, err := utils.ExecuteJSONCommand("nvme", "resv-report", devicePath, "-o", "json")
+	if err != nil {
+		log.Printf("Error running resv-report %s -o json: %s\n", devicePath, err)
+	}
+
+	return reservationReport
+}
+
+func getSupportedLogPagesData(devicePath string) gjson.Result {
+	supportedLogPages, err := utils.ExecuteJSONCommand("nvme", "supported-log-pages", devicePath, "-o", "json")
+	if err != nil {
+		log.Printf("Error running supported-log-pages %s -o json: %s\n", devicePath, err)
+	}
+
+	return supportedLogPages
+}
+
+func getEffectsLogData(devicePath string) gjson.Result {
+	effectsLog, err := utils.ExecuteJSONCommand("nvme", "effects-log", devicePath, "-o", "json")
+	if err != nil {
+		log.Printf("Error running effects-log %s -o json: %s\n", devicePath, err)
+	}
+
+	return effectsLog
+}
+
+// skipUnsupportedLogPage wraps a log page getter, returning an empty result
+// without running the command for the devices which do not support the log page.
+func skipUnsupportedLogPage(
+	capabilities *pkg.CapabilityMetricCollector,
+	lid uint64,
+	getData func(string) gjson.Result,
+) func(string) gjson.Result {
+	return func(devicePath string) gjson.Result {
+		if !capabilities.LogPageSupported(devicePath, lid) {
+			return pkg.SkippedResult()
+		}
+
+		return getData(devicePath)
+	}
+}
+
+func getAnaLogData(devicePath string) gjson.Result {
+	anaLog, err := utils.ExecuteJSONCommand("nvme", "ana-log", devicePath, "-o", "json")
+	if err != nil {
+		log.Printf("Error running ana-log %s -o json: %s\n", devicePath, err)
+	}
+
+	return anaLog
+}
+
+func getIoctlSmartLogData(devicePath string) gjson.Result {
+	smartLog, err := ioctl.SmartLog(devicePath)
+	if err != nil {
+		log.Printf("Error reading SMART log of %s: %s\n", devicePath, err)
+	}
+
+	return smartLog
+}
+
+func getIoctlOcpSmartLogData(devicePath string) gjson.Result {
+	ocpSmartLog, err := ioctl.OcpSmartLog(devicePath)
+	if err != nil {
+		log.Printf("OCP metrics not supported or error reading OCP SMART log of %s: %s "+
+			"(continuing with standard metrics)\n", devicePath, err)
+	}
+
+	// A log page without the OCP GUID means that the device does not implement it
+	if errors.Is(err, logpage.ErrGUIDMismatch) {
+		return pkg.SkippedResult()
+	}
+
+	return ocpSmartLog
+}
+
 type ProviderFactory struct {
 	valueType     prometheus.ValueType
 	defaultLabels []string
@@ -53,17 +328,18 @@ func (f *ProviderFactory) CreateLogMetricProvider(
 	)
 }
 
-func (f *ProviderFactory) CreateInfoMetricProvider(
+// CreateOptionalLogMetricProvider creates the provider of a field that not every
+// device or nvme-cli version reports, which is skipped instead of exported as zero when missing.
+func (f *ProviderFactory) CreateOptionalLogMetricProvider(
 	fqName string,
 	help string,
 	jsonKey string,
-	infoLabels []string,
 ) pkg.MetricProvider {
-	return pkg.NewMetricProvider(
+	return pkg.NewOptionalMetricProvider(
 		prometheus.NewDesc(
 			fqName,
 			help,
-			infoLabels,
+			f.defaultLabels,
 			nil,
 		),
 		f.valueType,
@@ -71,7 +347,77 @@ func (f *ProviderFactory) CreateInfoMetricProvider(
 	)
 }
 
-func newNvmeCollector(collectorStates map[string]bool) prometheus.Collector {
+func (f *ProviderFactory) CreateInfoMetricProvider(
+	fqName string,
+	help string,
+	value func(*pkg.Namespace) (float64, bool),
+	infoLabels []string,
+) pkg.NamespaceMetricProvider {
+	return pkg.NewNamespaceMetricProvider(
+		prometheus.NewDesc(
+			fqName,
+			help,
+			infoLabels,
+			nil,
+		),
+		f.valueType,
+		value,
+	)
+}
+
+func (f *ProviderFactory) CreateLogInfoMetricProvider(
+	fqName string,
+	help string,
+	infoLabels []string,
+	labelValues func(gjson.Result) ([]string, bool),
+) pkg.LogInfoMetricProvider {
+	return pkg.NewLogInfoMetricProvider(
+		prometheus.NewDesc(
+			fqName,
+			help,
+			append(slices.Clone(f.defaultLabels), infoLabels...),
+			nil,
+		),
+		labelValues,
+	)
+}
+
+// optionalValue returns the value of an optional namespace field,
+// reporting whether the data source provided it.
+func optionalValue(value *int64) (float64, bool) {
+	if value == nil {
+		return 0, false
+	}
+
+	return float64(*value), true
+}
+
+// firstJSONString returns the value of the first key present in the data,
+// for fields renamed across nvme-cli versions.
+func firstJSONString(data gjson.Result, keys ...string) string {
+	for _, key := range keys {
+		if result := data.Get(key); result.Exists() {
+			return result.String()
+		}
+	}
+
+	return ""
+}
+
+// _trackerCollectorName is the collector name of the unsupported command tracker,
+// which has no flag since it runs whenever the nvme-cli backend is used.
+const _trackerCollectorName = "unsupported_commands"
+
+// _persistentEventRefreshInterval is the minimum delay between two reads of the persistent
+// event log of a controller, which is read whole each time.
+const _persistentEventRefreshInterval = 5 * time.Minute
+
+func newNvmeCollector(
+	collectorStates map[string]bool,
+	backend string,
+	sysfsPath string,
+	customConfig *pkg.CustomConfig,
+) prometheus.Collector {
 	labels := []string{"device"}
 	infoLabels := []string{"device", "generic_path", "firmware", "model_number", "serial_number"}
 
@@ -86,35 +432,45 @@ func newNvmeCollector(collectorStates map[string]bool) prometheus.Collector {
 	}
 
 	// Info metrics
-	infoMetricProviders := []pkg.MetricProvider{
+	infoMetricProviders := []pkg.NamespaceMetricProvider{
 		gaugeValueFactory.CreateInfoMetricProvider(
 			"nvme_namespace",
 			"NVMe namespace identifier",
-			"NameSpace",
+			func(namespace *pkg.Namespace) (float64, bool) {
+				return float64(namespace.NSID), true
+			},
 			infoLabels,
 		),
 		gaugeValueFactory.CreateInfoMetricProvider(
 			"nvme_used_bytes",
 			"Used storage capacity in bytes",
-			"UsedBytes",
+			func(namespace *pkg.Namespace) (float64, bool) {
+				return optionalValue(namespace.UsedBytes)
+			},
 			infoLabels,
 		),
 		gaugeValueFactory.CreateInfoMetricProvider(
 			"nvme_maximum_lba",
 			"Maximum Logical Block Address",
-			"MaximumLBA",
+			func(namespace *pkg.Namespace) (float64, bool) {
+				return optionalValue(namespace.MaximumLBA)
+			},
 			infoLabels,
 		),
 		gaugeValueFactory.CreateInfoMetricProvider(
 			"nvme_physical_size",
 			"Physical size in bytes",
-			"PhysicalSize",
+			func(namespace *pkg.Namespace) (float64, bool) {
+				return optionalValue(namespace.PhysicalSize)
+			},
 			infoLabels,
 		),
 		gaugeValueFactory.CreateInfoMetricProvider(
 			"nvme_sector_size",
 			"Sector size in bytes",
-			"SectorSize",
+			func(namespace *pkg.Namespace) (float64, bool) {
+				return optionalValue(namespace.SectorSize)
+			},
 			infoLabels,
 		),
 	}
@@ -367,6 +723,12 @@ func newNvmeCollector(collectorStates map[string]bool) prometheus.Collector {
 			"Health indicator of the power loss protection capacitor (vendor-specific scale)",
 			"Capacitor health",
 		),
+		gaugeValueFactory.CreateOptionalLogMetricProvider(
+			"nvme_plp_health",
+			"Health indicator of the power loss protection as a percentage (0-100), "+
+				"named PLP Health since OCP 2.5",
+			"PLP Health",
+		),
 		counterValueFactory.CreateLogMetricProvider(
 			"nvme_unaligned_io",
 			"Total number of unaligned I/O operations performed",
@@ -397,11 +759,6 @@ func newNvmeCollector(collectorStates map[string]bool) prometheus.Collector {
 			"Version number of the OCP SMART log page specification",
 			"Log page version",
 		),
-		gaugeValueFactory.CreateLogMetricProvider(
-			"nvme_log_page_guid",
-			"GUID (Globally Unique Identifier) of the OCP SMART log page",
-			"Log page GUID",
-		),
 		gaugeValueFactory.CreateLogMetricProvider(
 			"nvme_errata_version_field",
 			"Errata version field from the OCP specification version",
@@ -427,6 +784,11 @@ func newNvmeCollector(collectorStates map[string]bool) prometheus.Collector {
 			"NVMe base specification errata version supported by the device",
 			"NVMe Errata Version",
 		),
+		gaugeValueFactory.CreateOptionalLogMetricProvider(
+			"nvme_nvme_command_set_errata_version",
+			"NVMe command set specification errata version supported by the device",
+			"NVMe Command Set Errata Version",
+		),
 		counterValueFactory.CreateLogMetricProvider(
 			"nvme_pcie_link_retraining_count",
 			"Total number of PCIe link retraining events",
@@ -439,23 +801,476 @@ func newNvmeCollector(collectorStates map[string]bool) prometheus.Collector {
 		),
 	}
 
+	// OCP error recovery log metrics, read per controller
+	ocpErrorRecoveryFactory := ProviderFactory{
+		valueType:     prometheus.GaugeValue,
+		defaultLabels: []string{"controller"},
+	}
+
+	ocpErrorRecoveryMetricProviders := []pkg.MetricProvider{
+		ocpErrorRecoveryFactory.CreateLogMetricProvider(
+			"nvme_ocp_panic_reset_wait_time_milliseconds",
+			"Time in milliseconds the host should wait for the device to reset after a panic",
+			"Panic Reset Wait Time",
+		),
+		ocpErrorRecoveryFactory.CreateLogMetricProvider(
+			"nvme_ocp_panic_reset_action",
+			"Reset actions the host should take to recover the device from a panic (bitmask)",
+			"Panic Reset Action",
+		),
+		ocpErrorRecoveryFactory.CreateLogMetricProvider(
+			"nvme_ocp_device_recovery_action_1",
+			"First action the host should take to recover the device after a panic",
+			"Device Recovery Action 1",
+		),
+		ocpErrorRecoveryFactory.CreateLogMetricProvider(
+			"nvme_ocp_device_recovery_action_2",
+			"Second action the host should take if the first recovery action did not recover the device",
+			"Device Recovery Action 2",
+		),
+		ocpErrorRecoveryFactory.CreateLogMetricProvider(
+			"nvme_ocp_device_recovery_action_2_timeout",
+			"Time the host should wait for the second recovery action to complete",
+			"Device Recovery Action 2 Timeout",
+		),
+		ocpErrorRecoveryFactory.CreateLogMetricProvider(
+			"nvme_ocp_error_recovery_device_capabilities",
+			"Error recovery capabilities of the device (bitmask)",
+			"Device Capabilities",
+		),
+		ocpErrorRecoveryFactory.CreateLogMetricProvider(
+			"nvme_ocp_vendor_specific_recovery_opcode",
+			"Opcode of the vendor specific command the host should issue to recover the device",
+			"Vendor Specific Recovery Opcode",
+		),
+		ocpErrorRecoveryFactory.CreateLogMetricProvider(
+			"nvme_ocp_vendor_specific_command_timeout",
+			"Time the host should wait for the vendor specific recovery command to complete",
+			"Vendor Specific Command Timeout",
+		),
+	}
+
+	// OCP smart-log string fields, exported as info labels
+	ocpLogInfoMetricProviders := []pkg.LogInfoMetricProvider{
+		gaugeValueFactory.CreateLogInfoMetricProvider(
+			"nvme_ocp_smart_log_info",
+			"OCP SMART log page version information, value is always 1",
+			[]string{
+				"log_page_guid",
+				"log_page_version",
+				"dssd_spec_version",
+				"nvme_errata_version",
+				"nvme_command_set_errata_version",
+				"lowest_permitted_firmware_revision",
+			},
+			func(data gjson.Result) ([]string, bool) {
+				if !data.Get("Log page GUID").Exists() && !data.Get("Major Version Field").Exists() {
+					return nil, false
+				}
+
+				dssdVersion := ""
+				if data.Get("Major Version Field").Exists() {
+					dssdVersion = fmt.Sprintf("%d.%d.%d.%d",
+						data.Get("Major Version Field").Int(),
+						data.Get("Minor Version Field").Int(),
+						data.Get("Point Version Field").Int(),
+						data.Get("Errata Version Field").Int(),
+					)
+				}
+
+				return []string{
+					strings.ToLower(data.Get("Log page GUID").String()),
+					data.Get("Log page version").String(),
+					dssdVersion,
+					firstJSONString(data, "NVMe Errata Version", "NVMe base errata version"),
+					firstJSONString(data, "NVMe Command Set Errata Version", "NVMe command set errata version"),
+					strings.TrimSpace(data.Get("Lowest Permitted Firmware Revision").String()),
+				}, true
+			},
+		),
+	}
+
+	// Endurance group log metrics
+	enduranceLabels := []string{"controller", "endurance_group"}
+
+	enduranceGaugeFactory := ProviderFactory{
+		valueType:     prometheus.GaugeValue,
+		defaultLabels: enduranceLabels,
+	}
+
+	enduranceCounterFactory := ProviderFactory{
+		valueType:     prometheus.CounterValue,
+		defaultLabels: enduranceLabels,
+	}
+
+	enduranceMetricProviders := []pkg.MetricProvider{
+		enduranceGaugeFactory.CreateLogMetricProvider(
+			"nvme_endurance_group_critical_warning",
+			"Critical warnings for the endurance group state. Bits indicate spare capacity, "+
+				"degraded reliability, or read-only mode",
+			"critical_warning",
+		),
+		enduranceGaugeFactory.CreateLogMetricProvider(
+			"nvme_endurance_group_avail_spare",
+			"Available spare capacity of the endurance group as a normalized percentage (0-100)",
+			"avl_spare",
+		),
+		enduranceGaugeFactory.CreateLogMetricProvider(
+			"nvme_endurance_group_avail_spare_threshold",
+			"Available spare capacity threshold of the endurance group",
+			"avl_spare_threshold",
+		),
+		enduranceGaugeFactory.CreateLogMetricProvider(
+			"nvme_endurance_group_percent_used",
+			"Vendor-specific estimate of the percentage of the endurance group life used (0-255)",
+			"percent_used",
+		),
+		enduranceGaugeFactory.CreateLogMetricProvider(
+			"nvme_endurance_group_endurance_estimate",
+			"Estimated number of 1,000,000,000 bytes units that may be written to the endurance group "+
+				"over its life",
+			"endurance_estimate",
+		),
+		enduranceCounterFactory.CreateLogMetricProvider(
+			"nvme_endurance_group_data_units_read",
+			"Total number of 512-byte data units read from the endurance group by the host",
+			"data_units_read",
+		),
+		enduranceCounterFactory.CreateLogMetricProvider(
+			"nvme_endurance_group_data_units_written",
+			"Total number of 512-byte data units written to the endurance group by the host",
+			"data_units_written",
+		),
+		enduranceCounterFactory.CreateLogMetricProvider(
+			"nvme_endurance_group_media_units_written",
+			"Total number of 512-byte data units written to the media of the endurance group, "+
+				"including the write amplification",
+			"media_units_written",
+		),
+		enduranceCounterFactory.CreateLogMetricProvider(
+			"nvme_endurance_group_host_read_commands",
+			"Total number of read commands completed by the endurance group",
+			"host_read_cmds",
+		),
+		enduranceCounterFactory.CreateLogMetricProvider(
+			"nvme_endurance_group_host_write_commands",
+			"Total number of write commands completed by the endurance group",
+			"host_write_cmds",
+		),
+		enduranceCounterFactory.CreateLogMetricProvider(
+			"nvme_endurance_group_media_errors",
+			"Total number of unrecovered data integrity errors detected in the endurance group",
+			"media_data_integrity_err",
+		),
+		enduranceCounterFactory.CreateLogMetricProvider(
+			"nvme_endurance_group_num_err_log_entries",
+			"Lifetime number of error log entries related to the endurance group",
+			"num_err_info_log_entries",
+		),
+		enduranceGaugeFactory.CreateLogMetricProvider(
+			"nvme_endurance_group_total_capacity_bytes",
+			"Total capacity of the endurance group in bytes",
+			"total_end_grp_capacity",
+		),
+		enduranceGaugeFactory.CreateLogMetricProvider(
+			"nvme_endurance_group_unallocated_capacity_bytes",
+			"Capacity of the endurance group not allocated to namespaces in bytes",
+			"unalloc_end_grp_capacity",
+		),
+	}
+
+	// FDP statistics log metrics
+	fdpMetricProviders := []pkg.MetricProvider{
+		enduranceCounterFactory.CreateLogMetricProvider(
+			"nvme_fdp_host_bytes_with_metadata_written",
+			"Total number of bytes with metadata written by the host to the FDP endurance group",
+			"hbmw",
+		),
+		enduranceCounterFactory.CreateLogMetricProvider(
+			"nvme_fdp_media_bytes_with_metadata_written",
+			"Total number of bytes with metadata written to the media of the FDP endurance group, "+
+				"including the write amplification",
+			"mbmw",
+		),
+		enduranceCounterFactory.CreateLogMetricProvider(
+			"nvme_fdp_media_bytes_erased",
+			"Total number of bytes erased from the media of the FDP endurance group",
+			"mbe",
+		),
+	}
+
+	// forgetters clear the data cached per device, the collectors caching data add theirs
+	var forgetters []func(string)
+
+	// The commands failing on a device are skipped until their retry time,
+	// a firmware update clears them along with the discovered capabilities and the cached data
+	tracker := pkg.NewUnsupportedCommandTracker(func(devicePath string) {
+		for _, forget := range forgetters {
+			forget(devicePath)
+		}
+	})
+
+	// The capabilities discovered through nvme-cli let the log page getters skip the unsupported log pages.
+	// The controllers failing the discovery are retried with the backoff of the tracker.
+	capabilities := pkg.NewCapabilityMetricCollector(
+		tracker.Track("id-ctrl", getIdentifyControllerData),
+		tracker.Track("supported-log-pages", getSupportedLogPagesData),
+		tracker.Track("effects-log", getEffectsLogData),
+	)
+	forgetters = append(forgetters, capabilities.Forget)
+
+	// optionalLogPage wraps the getter of a log page that devices may not support
+	optionalLogPage := func(lid uint64, command string, getData func(string) gjson.Result) func(string) gjson.Result {
+		return tracker.Track(command, skipUnsupportedLogPage(capabilities, lid, getData))
+	}
+
+	// The getters taking parameters track each command separately, by its arguments
+	getEnduranceLog := func(devicePath string, groupID int64) gjson.Result {
+		command := "endurance-log --group-id=" + strconv.FormatInt(groupID, 10)
+
+		return tracker.Track(command, func(devicePath string) gjson.Result {
+			return getEnduranceLogData(devicePath, groupID)
+		})(devicePath)
+	}
+
+	getFdpLog := func(subcommand string, devicePath string, groupID int64) gjson.Result {
+		command := "fdp " + subcommand + " --endgrp-id=" + strconv.FormatInt(groupID, 10)
+
+		return tracker.Track(command, func(devicePath string) gjson.Result {
+			return getFdpLogData(subcommand, devicePath, groupID)
+		})(devicePath)
+	}
+
+	getFeature := func(devicePath string, featureID int, cdw11 uint32) gjson.Result {
+		command := fmt.Sprintf("get-feature --feature-id=0x%02x", featureID)
+		if cdw11 != 0 {
+			command += fmt.Sprintf(" --cdw11=0x%x", cdw11)
+		}
+
+		return tracker.Track(command, func(devicePath string) gjson.Result {
+			return getFeatureData(devicePath, featureID, cdw11)
+		})(devicePath)
+	}
+
+	getIdentifyNamespace := tracker.Track("id-ns", getIdentifyNamespaceData)
+
+	// Select the data sources of the backend. The SMART log is not tracked,
+	// so that its failures are reported by the collector success on every scrape.
+	getDevices := pkg.GetDevices
+	getSmartLog := getSmartLogData
+	getOcpSmartLog := optionalLogPage(pkg.LogPageOcpSmart, "ocp smart-add-log", getOcpSmartLogData)
+	getAnaLog := optionalLogPage(pkg.LogPageAna, "ana-log", getAnaLogData)
+
+	switch backend {
+	case _backendSysfs:
+		getDevices = func() []*pkg.Subsystem {
+			return pkg.GetSysfsDevices(sysfsPath, nil)
+		}
+		getAnaLog = nil
+	case _backendIoctl:
+		getDevices = func() []*pkg.Subsystem {
+			return pkg.GetSysfsDevices(sysfsPath, ioctl.NamespaceUsedBytes)
+		}
+		getSmartLog = getIoctlSmartLogData
+		getOcpSmartLog = tracker.Track("ocp smart-add-log", getIoctlOcpSmartLogData)
+		getAnaLog = nil
+	}
+
+	// The capabilities of the controllers are discovered when they are first listed,
+	// before the collectors run, and the namespaces are mapped to their controller on each listing
+	if backend == _backendNVMeCLI {
+		listDevices := getDevices
+		getDevices = func() []*pkg.Subsystem {
+			subsystems := listDevices()
+			capabilities.Discover(subsystems)
+
+			return subsystems
+		}
+	}
+
 	// Build collectors based on enabled states
-	collectors := []pkg.MetricCollector{}
+	collectors := []pkg.NamedMetricCollector{}
+	addCollector := func(name string, collector pkg.MetricCollector) {
+		collectors = append(collectors, pkg.NewNamedMetricCollector(name, collector))
+	}
+
+	// The tracker comes first, to clear the unsupported commands of the updated devices
+	// before the other collectors run them. The sysfs backend runs no commands.
+	if backend != _backendSysfs {
+		addCollector(_trackerCollectorName, tracker)
+	}
+
+	// Add capabilities collector if enabled
+	if collectorStates["capabilities"] {
+		addCollector("capabilities", capabilities)
+	}
 
 	// Add info collector if enabled
 	if collectorStates["info"] {
-		collectors = append(collectors, pkg.NewInfoMetricCollector(infoMetricProviders))
+		addCollector("info", pkg.NewInfoMetricCollector(infoMetricProviders))
 	}
 
 	// Add smart-log collector if enabled
 	if collectorStates["smart"] {
-		collectors = append(collectors, pkg.NewLogMetricCollector(logMetricProviders, getSmartLogData))
+		addCollector("smart", pkg.NewLogMetricCollector(logMetricProviders, nil, getSmartLog))
 	}
 
 	// Add OCP collector if enabled (now enabled by default)
 	if collectorStates["ocp"] {
-		collectors = append(collectors, pkg.NewLogMetricCollector(ocpLogMetricProviders, getOcpSmartLogData))
+		addCollector("ocp", pkg.NewLogMetricCollector(
+			ocpLogMetricProviders,
+			ocpLogInfoMetricProviders,
+			getOcpSmartLog,
+		))
+	}
+
+	// Add OCP latency monitor collector if enabled
+	if collectorStates["ocp_latency"] {
+		addCollector("ocp_latency", pkg.NewOcpLatencyMetricCollector(
+			optionalLogPage(pkg.LogPageOcpLatencyMonitor, "ocp latency-monitor-log", getOcpLatencyMonitorLogData),
+		))
+	}
+
+	// Add OCP error recovery collector if enabled
+	if collectorStates["ocp_recovery"] {
+		addCollector("ocp_recovery", pkg.NewOcpErrorRecoveryMetricCollector(
+			ocpErrorRecoveryMetricProviders,
+			optionalLogPage(pkg.LogPageOcpErrorRecovery, "ocp error-recovery-log", getOcpErrorRecoveryLogData),
+		))
+	}
+
+	// Add OCP device capabilities collector if enabled
+	if collectorStates["ocp_capabilities"] {
+		addCollector("ocp_capabilities", pkg.NewOcpDeviceCapabilityMetricCollector(
+			optionalLogPage(pkg.LogPageOcpDeviceCapabilities, "ocp device-capability-log", getOcpDeviceCapabilityLogData),
+		))
+	}
+
+	// Add OCP unsupported requirements collector if enabled
+	if collectorStates["ocp_unsupported"] {
+		addCollector("ocp_unsupported", pkg.NewOcpUnsupportedRequirementsMetricCollector(
+			optionalLogPage(pkg.LogPageOcpUnsupportedRequirements, "ocp unsupported-reqs-log", getOcpUnsupportedReqsLogData),
+		))
+	}
+
+	// Add OCP telemetry string collector if enabled
+	if collectorStates["ocp_telemetry_strings"] {
+		addCollector("ocp_telemetry_strings", pkg.NewOcpTelemetryStringMetricCollector(
+			optionalLogPage(pkg.LogPageOcpTelemetryString, "ocp telemetry-string-log", getOcpTelemetryStringLogData),
+		))
+	}
+
+	// Add OCP hardware component collector if enabled
+	if collectorStates["ocp_hardware"] {
+		addCollector("ocp_hardware", pkg.NewOcpHardwareComponentMetricCollector(
+			optionalLogPage(pkg.LogPageOcpHardwareComponent, "ocp hardware-component-log", getOcpHardwareComponentLogData),
+		))
+	}
+
+	// Add vendor plugin collector if enabled
+	if collectorStates["vendor"] {
+		addCollector("vendor", pkg.NewVendorMetricCollector(sysfsPath, tracker.TrackCommands(getVendorLogData)))
+	}
+
+	// Add endurance group collector if enabled
+	if collectorStates["endurance"] {
+		addCollector("endurance", pkg.NewEnduranceMetricCollector(
+			enduranceMetricProviders,
+			capabilities.IdentifyController,
+			getEnduranceLog,
+		))
+	}
+
+	// Add FDP collector if enabled
+	if collectorStates["fdp"] {
+		addCollector("fdp", pkg.NewFdpMetricCollector(
+			fdpMetricProviders,
+			capabilities.IdentifyController,
+			getFdpLog,
+		))
+	}
+
+	// Add ZNS collector if enabled
+	if collectorStates["zns"] {
+		addCollector("zns", pkg.NewZnsMetricCollector(
+			sysfsPath,
+			tracker.Track("zns id-ns", getZnsIdentifyNamespaceData),
+			tracker.Track("zns report-zones", getZnsReportZonesData),
+		))
+	}
+
+	// Add persistent event log collector if enabled
+	if collectorStates["persistent-event"] {
+		addCollector("persistent-event", pkg.NewPersistentEventMetricCollector(
+			optionalLogPage(pkg.LogPagePersistentEvent, "persistent-event-log", getPersistentEventLogData),
+			_persistentEventRefreshInterval,
+		))
+	}
+
+	// Add sanitize and format progress collector if enabled
+	if collectorStates["sanitize"] {
+		addCollector("sanitize", pkg.NewSanitizeMetricCollector(
+			optionalLogPage(pkg.LogPageSanitizeStatus, "sanitize-log", getSanitizeLogData),
+			getIdentifyNamespace,
+		))
+	}
+
+	// Add power state collector if enabled
+	if collectorStates["power"] {
+		addCollector("power", pkg.NewPowerMetricCollector(capabilities.IdentifyController, getFeature))
+	}
+
+	// Add features collector if enabled
+	if collectorStates["features"] {
+		features := pkg.NewFeaturesMetricCollector(getSmartLog, getFeature)
+		forgetters = append(forgetters, features.Forget)
+
+		addCollector("features", features)
+	}
+
+	// Add reservation collector if enabled
+	if collectorStates["reservation"] {
+		addCollector("reservation", pkg.NewReservationMetricCollector(
+			getIdentifyNamespace,
+			tracker.Track("resv-report", func(devicePath string) gjson.Result {
+				if !capabilities.IOCommandSupported(devicePath, pkg.OpcodeReservationReport) {
+					return pkg.SkippedResult()
+				}
+
+				return getReservationReportData(devicePath)
+			}),
+		))
+	}
+
+	// Add topology collector if enabled
+	if collectorStates["topology"] {
+		addCollector("topology", pkg.NewTopologyMetricCollector())
+	}
+
+	// Add fabrics collector if enabled
+	if collectorStates["fabrics"] {
+		addCollector("fabrics", pkg.NewFabricsMetricCollector(sysfsPath))
+	}
+
+	// Add multipath collector if enabled
+	if collectorStates["multipath"] {
+		addCollector("multipath", pkg.NewMultipathMetricCollector(sysfsPath, getAnaLog))
+	}
+
+	// Add hwmon collector if enabled
+	if collectorStates["hwmon"] {
+		addCollector("hwmon", pkg.NewHwmonMetricCollector(sysfsPath))
+	}
+
+	// Add the user-defined collectors of the custom collectors config
+	if customConfig != nil {
+		for _, collectorConfig := range customConfig.Collectors {
+			addCollector(collectorConfig.Name, pkg.NewCustomMetricCollector(
+				collectorConfig,
+				tracker.TrackCommands(getCustomCommandData),
+			))
+		}
 	}
 
-	return pkg.NewCompositeCollector(collectors)
+	return pkg.NewCompositeCollector(collectors, getDevices)
 }
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,6 +21,13 @@ import (
 
 const _minimumSupportedVersion = "2.3"
 
+// Backends used to discover devices and read their data.
+const (
+	_backendNVMeCLI = "nvme-cli"
+	_backendSysfs   = "sysfs"
+	_backendIoctl   = "ioctl"
+)
+
 var (
 	validationState = struct {
 		sync.RWMutex
@@ -45,6 +53,10 @@ type Collector struct {
 	defaultState bool
 	enabled      *bool
 	description  string
+
+	// backends lists the backends the collector can work with,
+	// nil means every backend is supported
+	backends []string
 }
 
 var (
@@ -53,6 +65,7 @@ var (
 			name:         "smart",
 			defaultState: true,
 			description:  "NVMe SMART log metrics",
+			backends:     []string{_backendNVMeCLI, _backendIoctl},
 		},
 		"info": {
 			name:         "info",
@@ -63,6 +76,123 @@ var (
 			name:         "ocp",
 			defaultState: true,
 			description:  "NVMe OCP (Open Compute Project) SMART log metrics",
+			backends:     []string{_backendNVMeCLI, _backendIoctl},
+		},
+		"ocp_latency": {
+			name:         "ocp_latency",
+			defaultState: false,
+			description:  "NVMe OCP latency monitor log metrics",
+			backends:     []string{_backendNVMeCLI},
+		},
+		"ocp_recovery": {
+			name:         "ocp_recovery",
+			defaultState: false,
+			description:  "NVMe OCP error recovery log metrics",
+			backends:     []string{_backendNVMeCLI},
+		},
+		"ocp_capabilities": {
+			name:         "ocp_capabilities",
+			defaultState: false,
+			description:  "NVMe OCP device capabilities log metrics",
+			backends:     []string{_backendNVMeCLI},
+		},
+		"ocp_unsupported": {
+			name:         "ocp_unsupported",
+			defaultState: false,
+			description:  "NVMe OCP unsupported requirements log metrics",
+			backends:     []string{_backendNVMeCLI},
+		},
+		"ocp_telemetry_strings": {
+			name:         "ocp_telemetry_strings",
+			defaultState: false,
+			description:  "NVMe OCP telemetry string log metrics",
+			backends:     []string{_backendNVMeCLI},
+		},
+		"ocp_hardware": {
+			name:         "ocp_hardware",
+			defaultState: false,
+			description:  "NVMe OCP hardware component inventory metrics",
+			backends:     []string{_backendNVMeCLI},
+		},
+		"vendor": {
+			name:         "vendor",
+			defaultState: false,
+			description:  "NVMe extended SMART metrics from the nvme-cli vendor plugins",
+			backends:     []string{_backendNVMeCLI},
+		},
+		"endurance": {
+			name:         "endurance",
+			defaultState: false,
+			description:  "NVMe endurance group log metrics",
+			backends:     []string{_backendNVMeCLI},
+		},
+		"fdp": {
+			name:         "fdp",
+			defaultState: false,
+			description:  "NVMe Flexible Data Placement statistics, usage and configuration metrics",
+			backends:     []string{_backendNVMeCLI},
+		},
+		"zns": {
+			name:         "zns",
+			defaultState: false,
+			description:  "NVMe Zoned Namespace zone state metrics",
+			backends:     []string{_backendNVMeCLI},
+		},
+		"persistent-event": {
+			name:         "persistent-event",
+			defaultState: false,
+			description:  "NVMe persistent event log metrics",
+			backends:     []string{_backendNVMeCLI},
+		},
+		"sanitize": {
+			name:         "sanitize",
+			defaultState: false,
+			description:  "NVMe sanitize status and format progress metrics",
+			backends:     []string{_backendNVMeCLI},
+		},
+		"power": {
+			name:         "power",
+			defaultState: false,
+			description:  "NVMe power state and APST metrics",
+			backends:     []string{_backendNVMeCLI},
+		},
+		"features": {
+			name:         "features",
+			defaultState: false,
+			description:  "NVMe Get Features values (temperature thresholds, write cache, queues, ...)",
+			backends:     []string{_backendNVMeCLI},
+		},
+		"reservation": {
+			name:         "reservation",
+			defaultState: false,
+			description:  "NVMe reservation status of shared namespaces",
+			backends:     []string{_backendNVMeCLI},
+		},
+		"capabilities": {
+			name:         "capabilities",
+			defaultState: false,
+			description:  "NVMe supported log pages, discovered once per controller when first listed",
+			backends:     []string{_backendNVMeCLI},
+		},
+		"topology": {
+			name:         "topology",
+			defaultState: true,
+			description:  "NVMe subsystem, controller and namespace topology metrics",
+		},
+		"fabrics": {
+			name:         "fabrics",
+			defaultState: true,
+			description:  "NVMe over Fabrics connection metrics from sysfs",
+		},
+		"multipath": {
+			name:         "multipath",
+			defaultState: true,
+			description:  "NVMe native multipath ANA state and I/O policy metrics",
+		},
+		"hwmon": {
+			name:         "hwmon",
+			defaultState: true,
+			description:  "NVMe temperature metrics from the hwmon sysfs interface",
 		},
 	}
 
@@ -192,6 +322,27 @@ func resolveCollectorStates() map[string]bool {
 	return states
 }
 
+// supportsBackend returns whether the collector can work with the given backend.
+func (c *Collector) supportsBackend(backend string) bool {
+	if c.backends == nil {
+		return true
+	}
+
+	return slices.Contains(c.backends, backend)
+}
+
+// filterCollectorStates disables the enabled collectors that cannot work
+// with the selected backend.
+func filterCollectorStates(states map[string]bool, backend string) {
+	for name, enabled := range states {
+		if enabled && !collectors[name].supportsBackend(backend) {
+			log.Printf("WARNING: collector %s is not supported by the %s backend, disabling it", name, backend)
+
+			states[name] = false
+		}
+	}
+}
+
 func printUsage() {
 	fmt.Println("nvme_exporter - Prometheus exporter for NVMe device metrics")
 	fmt.Println("\nExports NVMe SMART log and OCP SMART log metrics in Prometheus format.")
@@ -208,6 +359,15 @@ func printUsage() {
 	fmt.Println("        Address on which to expose metrics and web interface (default \":9998\")")
 	fmt.Println("  --web.telemetry-path string")
 	fmt.Println("        Path under which to expose metrics (default \"/metrics\")")
+	fmt.Println("\nBackend options:")
+	fmt.Println("  --backend string")
+	fmt.Println("        Backend used to discover devices and read their data: nvme-cli, ioctl or sysfs " +
+		"(default \"nvme-cli\")")
+	fmt.Println("        The ioctl backend issues the admin commands directly and does not require nvme-cli")
+	fmt.Println("        The sysfs backend does not require nvme-cli nor root, " +
+		"but only supports the info and hwmon collectors")
+	fmt.Println("  --path.sysfs string")
+	fmt.Println("        Sysfs mountpoint (default \"/sys\")")
 	fmt.Println("\nCollector options:")
 	fmt.Println("  --collector.<name>")
 	fmt.Println("        Enable the specified collector (enabled by default)")
@@ -215,6 +375,9 @@ func printUsage() {
 	fmt.Println("        Disable the specified collector")
 	fmt.Println("  --collector.disable-defaults")
 	fmt.Println("        Disable all default collectors")
+	fmt.Println("  --collector.custom.config-file string")
+	fmt.Println("        YAML file declaring user-defined collectors mapping nvme-cli JSON fields onto metrics " +
+		"(nvme-cli backend only)")
 	fmt.Println("\nAvailable collectors:")
 
 	for name, collector := range collectors {
@@ -223,7 +386,7 @@ func printUsage() {
 			defaultStr = " (enabled by default)"
 		}
 
-		fmt.Printf("  %-10s %s%s\n", name, collector.description, defaultStr)
+		fmt.Printf("  %-22s %s%s\n", name, collector.description, defaultStr)
 	}
 
 	fmt.Println("\nExamples:")
@@ -235,9 +398,20 @@ func printUsage() {
 	fmt.Println("  nvme_exporter --no-collector.ocp")
 	fmt.Println("\n  # Only collect SMART metrics (disable info and OCP)")
 	fmt.Println("  nvme_exporter --collector.disable-defaults --collector.smart")
+	fmt.Println("\n  # Read SMART and OCP logs without forking nvme-cli")
+	fmt.Println("  nvme_exporter --backend=ioctl")
+	fmt.Println("\n  # Collect temperatures on hosts without nvme-cli")
+	fmt.Println("  nvme_exporter --backend=sysfs")
+	fmt.Println("\n  # Export vendor fields declared in a config file")
+	fmt.Println("  nvme_exporter --collector.custom.config-file=/etc/nvme_exporter/custom.yml")
 }
 
-func validatePrerequisites() {
+func validatePrerequisites(backend string) {
+	// The sysfs backend only reads world-readable attributes
+	if backend == _backendSysfs {
+		return
+	}
+
 	// Validate current user
 	err := utils.CheckCurrentUser("root")
 	if err != nil {
@@ -248,6 +422,11 @@ func validatePrerequisites() {
 		return
 	}
 
+	// The ioctl backend issues the admin commands without nvme-cli
+	if backend == _backendIoctl {
+		return
+	}
+
 	// Check for nvme-cli version
 	validateNVMeCLI()
 }
@@ -287,6 +466,71 @@ func validateNVMeCLI() {
 	log.Printf("NVMe cli version %s detected and supported", version)
 }
 
+// customDescs exposes the descriptors of a custom collector to a registry,
+// to check them against the descriptors of the built-in collectors.
+type customDescs struct {
+	*pkg.CustomMetricCollector
+}
+
+// Collect sends no metrics, customDescs is only registered to check its descriptors.
+func (customDescs) Collect(chan<- prometheus.Metric) {}
+
+// checkCustomConfig rejects the custom collectors clashing with the built-in ones:
+// a collector name already taken, or a metric name already exported by a built-in collector.
+// Every built-in collector is checked, so enabling a collector later cannot break the config.
+func checkCustomConfig(customConfig *pkg.CustomConfig, sysfsPath string) error {
+	builtinStates := make(map[string]bool, len(collectors))
+	for name := range collectors {
+		builtinStates[name] = true
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(scrapeFailuresTotal, newNvmeCollector(builtinStates, _backendNVMeCLI, sysfsPath, nil))
+
+	for _, collectorConfig := range customConfig.Collectors {
+		if _, found := collectors[collectorConfig.Name]; found || collectorConfig.Name == _trackerCollectorName {
+			return fmt.Errorf("collector %s: name already taken by a built-in collector", collectorConfig.Name)
+		}
+
+		err := registry.Register(customDescs{pkg.NewCustomMetricCollector(collectorConfig, nil)})
+		if err != nil {
+			return fmt.Errorf("collector %s: metric name already taken by a built-in collector: %w",
+				collectorConfig.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// loadCustomConfig loads the user-defined collectors, exiting if the config file is invalid.
+// The custom collectors run nvme-cli commands, so they are skipped with the other backends.
+func loadCustomConfig(path string, backend string, sysfsPath string) *pkg.CustomConfig {
+	if path == "" {
+		return nil
+	}
+
+	if backend != _backendNVMeCLI {
+		log.Printf("WARNING: custom collectors are not supported by the %s backend, ignoring %s", backend, path)
+
+		return nil
+	}
+
+	customConfig, err := pkg.LoadCustomConfig(path)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
+	if err := checkCustomConfig(customConfig, sysfsPath); err != nil {
+		log.Fatalf("invalid custom collectors config %s: %s", path, err)
+	}
+
+	for _, collectorConfig := range customConfig.Collectors {
+		log.Printf("Loaded custom collector %s (%d metrics)", collectorConfig.Name, len(collectorConfig.Metrics))
+	}
+
+	return customConfig
+}
+
 func main() {
 	// Initialize collector flags before parsing
 	initCollectorFlags()
@@ -296,8 +540,18 @@ func main() {
 	// Define flags following Prometheus node_exporter conventions
 	listenAddress := flag.String("web.listen-address", ":9998", "Address on which to expose metrics and web interface")
 	metricsPath := flag.String("web.telemetry-path", "/metrics", "Path under which to expose metrics")
+	backend := flag.String("backend", _backendNVMeCLI,
+		"Backend used to discover devices and read their data (nvme-cli, ioctl, sysfs)")
+	sysfsPath := flag.String("path.sysfs", "/sys", "Sysfs mountpoint")
+	customConfigFile := flag.String("collector.custom.config-file", "",
+		"YAML file declaring user-defined collectors (nvme-cli backend only)")
 	flag.Parse()
 
+	if !slices.Contains([]string{_backendNVMeCLI, _backendIoctl, _backendSysfs}, *backend) {
+		log.Fatalf("Unknown backend %q, supported backends are %s, %s and %s",
+			*backend, _backendNVMeCLI, _backendIoctl, _backendSysfs)
+	}
+
 	// Ensure metrics path starts with /
 	if !strings.HasPrefix(*metricsPath, "/") {
 		*metricsPath = "/" + *metricsPath
@@ -307,10 +561,11 @@ func main() {
 	prometheus.MustRegister(scrapeFailuresTotal)
 
 	// Validate prerequisites - log errors but don't exit
-	validatePrerequisites()
+	validatePrerequisites(*backend)
 
 	// Resolve collector states based on flags
 	collectorStates := resolveCollectorStates()
+	filterCollectorStates(collectorStates, *backend)
 
 	// Log enabled collectors
 	log.Printf("Enabled collectors:")
@@ -327,7 +582,11 @@ func main() {
 		scrapeFailuresTotal.Inc()
 	})
 
-	prometheus.MustRegister(newNvmeCollector(collectorStates))
+	log.Printf("Using %s backend", *backend)
+
+	customConfig := loadCustomConfig(*customConfigFile, *backend, *sysfsPath)
+
+	prometheus.MustRegister(newNvmeCollector(collectorStates, *backend, *sysfsPath, customConfig))
 	http.Handle(*metricsPath, promhttp.Handler())
 
 	// Add a landing page like node_exporter
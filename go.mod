@@ -5,6 +5,7 @@ go 1.23.4
 require (
 	github.com/prometheus/client_golang v1.23.2
 	github.com/tidwall/gjson v1.18.0
+	go.yaml.in/yaml/v2 v2.4.2
 )
 
 require (
@@ -17,7 +18,6 @@ require (
 	github.com/prometheus/procfs v0.16.1 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.0 // indirect
-	go.yaml.in/yaml/v2 v2.4.2 // indirect
 	golang.org/x/sys v0.35.0 // indirect
 	google.golang.org/protobuf v1.36.8 // indirect
 )
@@ -0,0 +1,314 @@
+package pkg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// Identifiers of the log pages read by the collectors.
+const (
+	LogPageAna                        = 0x0c
+	LogPagePersistentEvent            = 0x0d
+	LogPageSanitizeStatus             = 0x81
+	LogPageOcpSmart                   = 0xc0
+	LogPageOcpErrorRecovery           = 0xc1
+	LogPageOcpLatencyMonitor          = 0xc3
+	LogPageOcpDeviceCapabilities      = 0xc4
+	LogPageOcpUnsupportedRequirements = 0xc5
+	LogPageOcpHardwareComponent       = 0xc6
+	LogPageOcpTelemetryString         = 0xc9
+)
+
+// OpcodeReservationReport is the opcode of the Reservation Report I/O command.
+const OpcodeReservationReport = 0x0e
+
+const (
+	// lpaCommandEffectsBit is the bit of the LPA field of Identify Controller
+	// reporting whether the Commands Supported and Effects log page is supported.
+	lpaCommandEffectsBit = 1 << 1
+	// lpaSupportedLogPagesBit is the bit of the LPA field of Identify Controller
+	// reporting whether the Supported Log Pages log page is supported.
+	lpaSupportedLogPagesBit = 1 << 5
+)
+
+// DeviceCapabilities holds the log pages and commands supported by a device.
+// A nil set means that the device does not report it, and everything is assumed to be supported.
+type DeviceCapabilities struct {
+	LogPages   map[uint64]bool
+	IOCommands map[uint64]bool
+
+	// IdentifyController is the Identify Controller JSON data the capabilities are discovered from
+	IdentifyController gjson.Result
+}
+
+// CapabilityMetricCollector implements ControllerMetricCollector and discovers the log pages
+// and commands supported by each controller, from Identify Controller, the Supported Log Pages log
+// page (00h) and the Commands Supported and Effects log page (05h). The discovery runs once per
+// controller and its results are cached, so that the getters of the other collectors can skip the
+// unsupported log pages instead of failing on every collection. The namespaces share the
+// capabilities of the controller they are attached to.
+// The supported log pages of each controller are sent as metrics.
+type CapabilityMetricCollector struct {
+	// getIdentifyData receives the controller device path and gets the Identify Controller JSON data
+	getIdentifyData func(string) gjson.Result
+	// getSupportedLogPagesData receives the controller device path and gets the supported log pages JSON data
+	getSupportedLogPagesData func(string) gjson.Result
+	// getEffectsData receives the controller device path and gets the commands supported and effects JSON data
+	getEffectsData func(string) gjson.Result
+
+	logPageSupportedDesc *prometheus.Desc
+
+	// mutex protects the fields below, since the registry may collect concurrently
+	mutex sync.Mutex
+	// controllers holds the controller name of each namespace device path
+	controllers map[string]string
+	// capabilities holds the capabilities discovered for each controller name
+	capabilities map[string]*DeviceCapabilities
+}
+
+// NewCapabilityMetricCollector initializes and returns a new CapabilityMetricCollector object.
+func NewCapabilityMetricCollector(
+	getIdentifyData func(string) gjson.Result,
+	getSupportedLogPagesData func(string) gjson.Result,
+	getEffectsData func(string) gjson.Result,
+) *CapabilityMetricCollector {
+	return &CapabilityMetricCollector{
+		getIdentifyData:          getIdentifyData,
+		getSupportedLogPagesData: getSupportedLogPagesData,
+		getEffectsData:           getEffectsData,
+		logPageSupportedDesc: prometheus.NewDesc(
+			"nvme_log_page_supported",
+			"Log page supported by the controller, from the Supported Log Pages log page",
+			[]string{"controller", "lid"},
+			nil,
+		),
+		controllers:  make(map[string]string),
+		capabilities: make(map[string]*DeviceCapabilities),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (cc *CapabilityMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cc.logPageSupportedDesc
+}
+
+// CollectControllerMetrics sends the log pages supported by the controller through the channel.
+func (cc *CapabilityMetricCollector) CollectControllerMetrics(
+	ch chan<- prometheus.Metric,
+	controller *Controller,
+) error {
+	if controller.Name == "" {
+		return nil
+	}
+
+	capabilities := cc.Capabilities("/dev/" + controller.Name)
+	if capabilities == nil {
+		return noDataError("/dev/"+controller.Name, gjson.Result{})
+	}
+
+	for lid := range capabilities.LogPages {
+		ch <- prometheus.MustNewConstMetric(
+			cc.logPageSupportedDesc,
+			prometheus.GaugeValue,
+			1,
+			controller.Name,
+			fmt.Sprintf("0x%02x", lid),
+		)
+	}
+
+	return nil
+}
+
+// Discover maps the namespaces of the subsystems to their controller and discovers
+// the capabilities of the controllers that are not cached yet.
+func (cc *CapabilityMetricCollector) Discover(subsystems []*Subsystem) {
+	controllers := make(map[string]string)
+
+	for _, subsystem := range subsystems {
+		for _, controller := range subsystem.Controllers {
+			if controller.Name == "" {
+				continue
+			}
+
+			for _, namespace := range controller.Namespaces {
+				controllers[namespace.DevicePath] = controller.Name
+			}
+		}
+	}
+
+	cc.mutex.Lock()
+	cc.controllers = controllers
+	cc.mutex.Unlock()
+
+	for _, subsystem := range subsystems {
+		for _, controller := range subsystem.Controllers {
+			if controller.Name != "" {
+				cc.Capabilities("/dev/" + controller.Name)
+			}
+		}
+	}
+}
+
+// Capabilities returns the capabilities of the device, those of its controller for a namespace,
+// discovering them on the first call. It returns nil if the controller could not be identified,
+// the discovery is then retried on the next call.
+func (cc *CapabilityMetricCollector) Capabilities(devicePath string) *DeviceCapabilities {
+	cc.mutex.Lock()
+	controller := cc.controllerName(devicePath)
+	capabilities, found := cc.capabilities[controller]
+	cc.mutex.Unlock()
+
+	if found {
+		return capabilities
+	}
+
+	// The commands run without holding the mutex, so that a slow device does not block the others
+	capabilities = cc.discover("/dev/" + controller)
+	if capabilities == nil {
+		return nil
+	}
+
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	// Keep the capabilities of a concurrent discovery, so that all the callers share them
+	if cached, found := cc.capabilities[controller]; found {
+		return cached
+	}
+
+	cc.capabilities[controller] = capabilities
+
+	return capabilities
+}
+
+// discover runs the discovery commands on the controller and returns its capabilities,
+// or nil if it could not be identified.
+func (cc *CapabilityMetricCollector) discover(devicePath string) *DeviceCapabilities {
+	identifyData := cc.getIdentifyData(devicePath)
+	if !identifyData.Exists() {
+		return nil
+	}
+
+	capabilities := &DeviceCapabilities{IdentifyController: identifyData}
+	lpa := identifyData.Get("lpa").Uint()
+
+	if lpa&lpaSupportedLogPagesBit != 0 {
+		if logPagesData := cc.getSupportedLogPagesData(devicePath); logPagesData.Exists() {
+			capabilities.LogPages = findIndexedKeys(logPagesData, "lid_")
+		}
+	}
+
+	if lpa&lpaCommandEffectsBit != 0 {
+		if effectsData := cc.getEffectsData(devicePath); effectsData.Exists() {
+			capabilities.IOCommands = findIndexedKeys(effectsData, "IOCS")
+		}
+	}
+
+	return capabilities
+}
+
+// controllerName returns the name of the controller of the device path. The devices that are
+// not known namespaces, such as the controllers, are their own controller. The mutex must be held.
+func (cc *CapabilityMetricCollector) controllerName(devicePath string) string {
+	if controller, found := cc.controllers[devicePath]; found {
+		return controller
+	}
+
+	return strings.TrimPrefix(devicePath, "/dev/")
+}
+
+// Forget clears the capabilities of the device controller, so that they are discovered again on the next call.
+func (cc *CapabilityMetricCollector) Forget(devicePath string) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	delete(cc.capabilities, cc.controllerName(devicePath))
+}
+
+// IdentifyController returns the cached Identify Controller JSON data of the device,
+// so that the collectors reading it do not run Identify Controller on every collection.
+// It returns an empty result if the device could not be identified.
+func (cc *CapabilityMetricCollector) IdentifyController(devicePath string) gjson.Result {
+	capabilities := cc.Capabilities(devicePath)
+	if capabilities == nil {
+		return gjson.Result{}
+	}
+
+	return capabilities.IdentifyController
+}
+
+// LogPageSupported returns whether the device supports the log page.
+func (cc *CapabilityMetricCollector) LogPageSupported(devicePath string, lid uint64) bool {
+	capabilities := cc.Capabilities(devicePath)
+	if capabilities == nil || capabilities.LogPages == nil {
+		return true
+	}
+
+	return capabilities.LogPages[lid]
+}
+
+// IOCommandSupported returns whether the device supports the I/O command.
+func (cc *CapabilityMetricCollector) IOCommandSupported(devicePath string, opcode uint64) bool {
+	capabilities := cc.Capabilities(devicePath)
+	if capabilities == nil || capabilities.IOCommands == nil {
+		return true
+	}
+
+	return capabilities.IOCommands[opcode]
+}
+
+// findIndexedKeys searches the JSON data for the keys made of the prefix and an index,
+// such as "lid_0x2" or "IOCS_14 (Reservation Report)" as printed by nvme-cli, and returns the set of indexes.
+func findIndexedKeys(data gjson.Result, prefix string) map[uint64]bool {
+	indexes := make(map[uint64]bool)
+
+	var search func(gjson.Result)
+
+	search = func(value gjson.Result) {
+		value.ForEach(func(key, child gjson.Result) bool {
+			if index, found := parseIndexedKey(key.String(), prefix); found {
+				indexes[index] = true
+			}
+
+			if child.IsObject() || child.IsArray() {
+				search(child)
+			}
+
+			return true
+		})
+	}
+
+	search(data)
+
+	return indexes
+}
+
+// parseIndexedKey returns the index of a key made of the prefix and a decimal or hex index.
+func parseIndexedKey(key string, prefix string) (uint64, bool) {
+	index, found := strings.CutPrefix(key, prefix)
+	if !found {
+		return 0, false
+	}
+
+	index = strings.TrimPrefix(index, "_")
+	if end := strings.IndexAny(index, " ("); end >= 0 {
+		index = index[:end]
+	}
+
+	base := 10
+	if hexIndex, found := strings.CutPrefix(index, "0x"); found {
+		index, base = hexIndex, 16
+	}
+
+	value, err := strconv.ParseUint(index, base, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return value, true
+}
@@ -0,0 +1,149 @@
+package pkg_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+// commandCounter counts the commands run on each device.
+type commandCounter struct {
+	mutex  sync.Mutex
+	counts map[string]int
+}
+
+// count records a command run on the device and returns the number of runs so far.
+func (cc *commandCounter) count(command string, devicePath string) int {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	if cc.counts == nil {
+		cc.counts = make(map[string]int)
+	}
+
+	cc.counts[command+" "+devicePath]++
+
+	return cc.counts[command+" "+devicePath]
+}
+
+// get returns the number of runs of the command on the device.
+func (cc *commandCounter) get(command string, devicePath string) int {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	return cc.counts[command+" "+devicePath]
+}
+
+func TestCapabilityMetricCollector(t *testing.T) {
+	t.Parallel()
+
+	commands := &commandCounter{}
+
+	capabilities := pkg.NewCapabilityMetricCollector(
+		func(devicePath string) gjson.Result {
+			commands.count("id-ctrl", devicePath)
+
+			// Supported Log Pages and Commands Supported and Effects log pages supported
+			return gjson.Parse(`{"lpa": 34}`)
+		},
+		func(devicePath string) gjson.Result {
+			commands.count("supported-log-pages", devicePath)
+
+			if devicePath == "/dev/nvme2" {
+				return gjson.Parse(`{"lid_0x00": {}, "lid_0x0c": {}, "lid_0xc0": {}}`)
+			}
+
+			return gjson.Parse(`{"lid_0x00": {}, "lid_0x0c": {}}`)
+		},
+		func(devicePath string) gjson.Result {
+			commands.count("effects-log", devicePath)
+
+			return gjson.Parse(`{"IOCS_14 (Reservation Report)": {"csupp": 1}}`)
+		},
+	)
+
+	subsystem := newMultipathTestSubsystem()
+
+	// The discovery runs once per controller, not per namespace
+	for range 2 {
+		capabilities.Discover([]*pkg.Subsystem{subsystem})
+	}
+
+	for _, devicePath := range []string{"/dev/nvme1", "/dev/nvme2"} {
+		for _, command := range []string{"id-ctrl", "supported-log-pages", "effects-log"} {
+			if runs := commands.get(command, devicePath); runs != 1 {
+				t.Errorf("%s ran %d times on %s, want 1", command, runs, devicePath)
+			}
+		}
+	}
+
+	// The namespaces share the capabilities of the controller they are attached to
+	if capabilities.LogPageSupported("/dev/nvme1n1", pkg.LogPageOcpSmart) {
+		t.Error("the OCP SMART log is supported by nvme1n1 but not by its controller nvme1")
+	}
+
+	if !capabilities.LogPageSupported("/dev/nvme1n2", pkg.LogPageOcpSmart) {
+		t.Error("the OCP SMART log is not supported by nvme1n2 but is by its controller nvme2")
+	}
+
+	if !capabilities.IOCommandSupported("/dev/nvme1n1", pkg.OpcodeReservationReport) {
+		t.Error("the reservation report is not supported by nvme1n1")
+	}
+
+	if commands.get("id-ctrl", "/dev/nvme1n1") != 0 || commands.get("id-ctrl", "/dev/nvme1n2") != 0 {
+		t.Error("the discovery ran on a namespace")
+	}
+
+	checkValues(t, gatherValues(t, newTestCollector("capabilities", capabilities, subsystem)), map[string]float64{
+		`nvme_log_page_supported{controller="nvme1",lid="0x0c"}`: 1,
+		`nvme_log_page_supported{controller="nvme2",lid="0xc0"}`: 1,
+	})
+
+	// Forgetting a namespace forgets the capabilities of its controller
+	capabilities.Forget("/dev/nvme1n1")
+	capabilities.Discover([]*pkg.Subsystem{subsystem})
+
+	if runs := commands.get("id-ctrl", "/dev/nvme1"); runs != 2 {
+		t.Errorf("id-ctrl ran %d times on /dev/nvme1 after Forget, want 2", runs)
+	}
+
+	if runs := commands.get("id-ctrl", "/dev/nvme2"); runs != 1 {
+		t.Errorf("id-ctrl ran %d times on /dev/nvme2 after forgetting nvme1, want 1", runs)
+	}
+}
+
+func TestCapabilityDiscoveryFailure(t *testing.T) {
+	t.Parallel()
+
+	commands := &commandCounter{}
+	tracker := pkg.NewUnsupportedCommandTracker(nil)
+
+	noData := func(string) gjson.Result {
+		return gjson.Result{}
+	}
+
+	capabilities := pkg.NewCapabilityMetricCollector(
+		tracker.Track("id-ctrl", func(devicePath string) gjson.Result {
+			commands.count("id-ctrl", devicePath)
+
+			return gjson.Result{}
+		}),
+		noData,
+		noData,
+	)
+
+	// The controllers that cannot be identified support everything, and their failed
+	// discovery is skipped until the retry time of the tracker
+	for range 3 {
+		if !capabilities.LogPageSupported("/dev/nvme0", pkg.LogPageOcpSmart) {
+			t.Error("the log pages of a controller without capabilities are not supported")
+		}
+	}
+
+	if runs := commands.get("id-ctrl", "/dev/nvme0"); runs != 1 {
+		t.Errorf("the failed id-ctrl ran %d times, want 1", runs)
+	}
+}
@@ -1,7 +1,10 @@
 package pkg
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tidwall/gjson"
@@ -9,23 +12,25 @@ import (
 	"github.com/E4-Computer-Engineering/nvme_exporter/pkg/utils"
 )
 
-// GetDevices queries the devices list through the shell
-// and returns an array of JSON results with the devices data.
+// GetDevices queries the verbose devices list through the shell
+// and returns the subsystems topology with the devices data.
 // This function handles both old flat structure and new nested structure
 // of nvme-cli JSON output.
-func GetDevices() []gjson.Result {
+func GetDevices() []*Subsystem {
 	// Check validation state before attempting to query devices
-	if validationChecker != nil && !validationChecker() {
-		if scrapeFailureIncrementer != nil {
-			scrapeFailureIncrementer()
-		}
+	if !checkValidation() {
+		return []*Subsystem{}
+	}
 
-		log.Printf("Skipping device query due to validation failure")
+	// nvme-cli 2.x only prints the nested structure in verbose mode,
+	// the plain list is kept as a fallback for the versions without it
+	devicesJSON, err := utils.ExecuteJSONCommand("nvme", "list", "-v", "-o", "json")
+	if err != nil {
+		log.Printf("Error running nvme list -v -o json, retrying without -v: %s\n", err)
 
-		return []gjson.Result{}
+		devicesJSON, err = utils.ExecuteJSONCommand("nvme", "list", "-o", "json")
 	}
 
-	devicesJSON, err := utils.ExecuteJSONCommand("nvme", "list", "-o", "json")
 	if err != nil {
 		log.Printf("Error running nvme list -o json: %s\n", err)
 
@@ -33,68 +38,22 @@ func GetDevices() []gjson.Result {
 			scrapeFailureIncrementer()
 		}
 
-		return []gjson.Result{}
+		return []*Subsystem{}
 	}
 
 	devices := devicesJSON.Get("Devices").Array()
 	if len(devices) == 0 {
-		return []gjson.Result{}
+		return []*Subsystem{}
 	}
 
 	// Check if we have the new nested structure (with Subsystems)
 	// or the old flat structure (with DevicePath)
 	firstDevice := devices[0]
 	if firstDevice.Get("Subsystems").Exists() {
-		// New nested structure - flatten it
-		return flattenNewStructure(devices)
-	}
-
-	// Old flat structure - return as is
-	return devices
-}
-
-// flattenNewStructure converts the new nested nvme-cli JSON structure
-// to a flat structure compatible with the rest of the code.
-func flattenNewStructure(devices []gjson.Result) []gjson.Result {
-	var flattened []gjson.Result
-
-	for _, device := range devices {
-		subsystems := device.Get("Subsystems").Array()
-		for _, subsystem := range subsystems {
-			controllers := subsystem.Get("Controllers").Array()
-			for _, controller := range controllers {
-				serialNumber := controller.Get("SerialNumber").String()
-				modelNumber := controller.Get("ModelNumber").String()
-				firmware := controller.Get("Firmware").String()
-
-				namespaces := controller.Get("Namespaces").Array()
-				for _, namespace := range namespaces {
-					namespaceName := namespace.Get("NameSpace").String()
-					generic := namespace.Get("Generic").String()
-
-					// Construct a flat JSON object compatible with old structure
-					flatJSON := map[string]interface{}{
-						"DevicePath":   "/dev/" + namespaceName,
-						"GenericPath":  generic,
-						"Firmware":     firmware,
-						"ModelNumber":  modelNumber,
-						"SerialNumber": serialNumber,
-						"NameSpace":    namespace.Get("NSID").Int(),
-						"UsedBytes":    namespace.Get("UsedBytes").Int(),
-						"MaximumLBA":   namespace.Get("MaximumLBA").Int(),
-						"PhysicalSize": namespace.Get("PhysicalSize").Int(),
-						"SectorSize":   namespace.Get("SectorSize").Int(),
-					}
-
-					// Convert map to JSON string and parse it as gjson.Result
-					jsonStr := utils.MapToJSONString(flatJSON)
-					flattened = append(flattened, gjson.Parse(jsonStr))
-				}
-			}
-		}
+		return parseNestedDevices(devices)
 	}
 
-	return flattened
+	return parseFlatDevices(devices)
 }
 
 // MetricCollector is the interface implemented by the objects contained
@@ -106,24 +65,91 @@ func flattenNewStructure(devices []gjson.Result) []gjson.Result {
 // Here the device data is injected in CollectMetrics, so that we can call
 // GetDevice once in CompositeCollector.Collect
 // (it is a shell function, so every call can potentially be "expensive").
+//
+// Depending on the scope of their data, collectors implement one or more of
+// NamespaceMetricCollector, ControllerMetricCollector and SubsystemMetricCollector.
+// The collect methods return an error when the data of the device could not be read,
+// which CompositeCollector reports through the collector success metric.
 type MetricCollector interface {
 	// Describe is the same as prometheus.Collector.Describe
 	Describe(descChan chan<- *prometheus.Desc)
+}
+
+// NamespaceMetricCollector is a MetricCollector whose metrics are per namespace.
+type NamespaceMetricCollector interface {
+	MetricCollector
 
 	// CollectMetrics does what prometheus.Collector.Collect does,
-	// but needs the device JSON data to prevent calling GetDevice
+	// but needs the namespace data to prevent calling GetDevice
 	// multiple times
-	CollectMetrics(metricChan chan<- prometheus.Metric, device gjson.Result)
+	CollectMetrics(metricChan chan<- prometheus.Metric, namespace *Namespace) error
+}
+
+// ControllerMetricCollector is a MetricCollector whose metrics are per controller.
+type ControllerMetricCollector interface {
+	MetricCollector
+
+	// CollectControllerMetrics is called once per controller
+	CollectControllerMetrics(metricChan chan<- prometheus.Metric, controller *Controller) error
+}
+
+// SubsystemMetricCollector is a MetricCollector whose metrics are per subsystem.
+type SubsystemMetricCollector interface {
+	MetricCollector
+
+	// CollectSubsystemMetrics is called once per subsystem
+	CollectSubsystemMetrics(metricChan chan<- prometheus.Metric, subsystem *Subsystem) error
 }
 
-// InfoMetricCollector implements MetricCollector and sends info metrics.
+// NamedMetricCollector is a MetricCollector with the name reported in the collector metrics.
+type NamedMetricCollector struct {
+	MetricCollector
+
+	Name string
+}
+
+// NewNamedMetricCollector returns the collector with its name.
+func NewNamedMetricCollector(name string, collector MetricCollector) NamedMetricCollector {
+	return NamedMetricCollector{MetricCollector: collector, Name: name}
+}
+
+// ErrSkipped is the error of a collector whose command was skipped for the device, because the
+// device does not support it or the command is waiting for its retry time. CompositeCollector
+// reports it as a success, so that only the failed commands are counted as failures.
+var ErrSkipped = errors.New("command skipped")
+
+// skippedResultMarker tells the result of a skipped command apart from the result of a failed command.
+const skippedResultMarker = "skipped"
+
+// SkippedResult returns the result of a getter skipping its command for the device.
+// Like the result of a failed command it does not exist, but noDataError reports it with ErrSkipped.
+func SkippedResult() gjson.Result {
+	return gjson.Result{Str: skippedResultMarker}
+}
+
+// IsSkipped returns whether the result is the result of a skipped command.
+func IsSkipped(result gjson.Result) bool {
+	return !result.Exists() && result.Str == skippedResultMarker
+}
+
+// noDataError returns the error of a collector whose data source returned no data for the device,
+// e.g. because the command failed, or ErrSkipped if the command was skipped.
+func noDataError(devicePath string, result gjson.Result) error {
+	if IsSkipped(result) {
+		return fmt.Errorf("%w for %s", ErrSkipped, devicePath)
+	}
+
+	return fmt.Errorf("no data for %s", devicePath)
+}
+
+// InfoMetricCollector implements NamespaceMetricCollector and sends info metrics.
 type InfoMetricCollector struct {
 	// InfoMetricProviders is the list of providers for the info metric collector
-	InfoMetricProviders []MetricProvider
+	InfoMetricProviders []NamespaceMetricProvider
 }
 
 // NewInfoMetricCollector initializes and returns a new InfoMetricCollector object.
-func NewInfoMetricCollector(providers []MetricProvider) *InfoMetricCollector {
+func NewInfoMetricCollector(providers []NamespaceMetricProvider) *InfoMetricCollector {
 	return &InfoMetricCollector{InfoMetricProviders: providers}
 }
 
@@ -134,45 +160,51 @@ func (ic *InfoMetricCollector) Describe(ch chan<- *prometheus.Desc) {
 	}
 }
 
-// CollectMetrics gets the devices data and sends all info metrics through the channel.
-func (ic *InfoMetricCollector) CollectMetrics(ch chan<- prometheus.Metric, device gjson.Result) {
-	devicePath := device.Get("DevicePath").String()
-	genericPath := device.Get("GenericPath").String()
-	firmware := device.Get("Firmware").String()
-	modelNumber := device.Get("ModelNumber").String()
-	serialNumber := device.Get("SerialNumber").String()
+// CollectMetrics gets the namespace data and sends all info metrics through the channel.
+func (ic *InfoMetricCollector) CollectMetrics(ch chan<- prometheus.Metric, namespace *Namespace) error {
+	controller := namespace.Controller
 
 	for _, infoProvider := range ic.InfoMetricProviders {
 		// Fetching the metric object is delegated to the provider
 		metric := infoProvider.GetMetric(
-			device,
-			devicePath,
-			genericPath,
-			firmware,
-			modelNumber,
-			serialNumber,
+			namespace,
+			namespace.DevicePath,
+			namespace.GenericPath,
+			controller.Firmware,
+			controller.ModelNumber,
+			controller.SerialNumber,
 		)
 		// Only send metric if it's not nil (handles cases where data is unavailable)
 		if metric != nil {
 			ch <- metric
 		}
 	}
+
+	return nil
 }
 
-// LogMetricCollector implements MetricCollector and sends smart log metrics.
+// LogMetricCollector implements NamespaceMetricCollector and sends smart log metrics.
 type LogMetricCollector struct {
 	// LogMetricProviders is the list of providers for the log metric collector
 	LogMetricProviders []MetricProvider
 
+	// LogInfoMetricProviders is the list of providers for the string fields of the log
+	LogInfoMetricProviders []LogInfoMetricProvider
+
 	// getData receives the devicePath and gets the log JSON data
 	getData func(string) gjson.Result
 }
 
 // NewLogMetricCollector initializes and returns a new LogMetricCollector object.
-func NewLogMetricCollector(providers []MetricProvider, getData func(string) gjson.Result) *LogMetricCollector {
+func NewLogMetricCollector(
+	providers []MetricProvider,
+	infoProviders []LogInfoMetricProvider,
+	getData func(string) gjson.Result,
+) *LogMetricCollector {
 	return &LogMetricCollector{
-		LogMetricProviders: providers,
-		getData:            getData,
+		LogMetricProviders:     providers,
+		LogInfoMetricProviders: infoProviders,
+		getData:                getData,
 	}
 }
 
@@ -181,17 +213,21 @@ func (lc *LogMetricCollector) Describe(ch chan<- *prometheus.Desc) {
 	for _, logProvider := range lc.LogMetricProviders {
 		ch <- logProvider.Desc
 	}
+
+	for _, logInfoProvider := range lc.LogInfoMetricProviders {
+		ch <- logInfoProvider.Desc
+	}
 }
 
 // CollectMetrics gets the smart log data and sends all log metrics through the channel.
-func (lc *LogMetricCollector) CollectMetrics(ch chan<- prometheus.Metric, device gjson.Result) {
-	devicePath := device.Get("DevicePath").String()
+func (lc *LogMetricCollector) CollectMetrics(ch chan<- prometheus.Metric, namespace *Namespace) error {
+	devicePath := namespace.DevicePath
 
 	jsonData := lc.getData(devicePath)
 
 	// If getData returns invalid data (e.g., OCP not supported), skip this collector
 	if !jsonData.Exists() {
-		return
+		return noDataError(devicePath, jsonData)
 	}
 
 	for _, logProvider := range lc.LogMetricProviders {
@@ -202,18 +238,50 @@ func (lc *LogMetricCollector) CollectMetrics(ch chan<- prometheus.Metric, device
 			ch <- metric
 		}
 	}
+
+	for _, logInfoProvider := range lc.LogInfoMetricProviders {
+		metric := logInfoProvider.GetMetric(jsonData, devicePath)
+		if metric != nil {
+			ch <- metric
+		}
+	}
+
+	return nil
 }
 
 // CompositeCollector implements prometheus.Collector interface,
 // wrapping a slice of other MetricCollector objects.
+// It also sends the success and the duration of each collector for each device.
 type CompositeCollector struct {
-	// collectors holds a simple list of MetricCollector objects
-	collectors []MetricCollector
+	// collectors holds a simple list of MetricCollector objects with their names
+	collectors []NamedMetricCollector
+
+	// getDevices returns the subsystems topology from the configured backend
+	// (e.g. GetDevices for nvme-cli)
+	getDevices func() []*Subsystem
+
+	successDesc  *prometheus.Desc
+	durationDesc *prometheus.Desc
 }
 
 // NewCompositeCollector initializes and returns a new CompositeCollector object.
-func NewCompositeCollector(collectors []MetricCollector) *CompositeCollector {
-	return &CompositeCollector{collectors: collectors}
+func NewCompositeCollector(collectors []NamedMetricCollector, getDevices func() []*Subsystem) *CompositeCollector {
+	return &CompositeCollector{
+		collectors: collectors,
+		getDevices: getDevices,
+		successDesc: prometheus.NewDesc(
+			"nvme_exporter_collector_success",
+			"Whether the collector succeeded for the device (1 = success)",
+			[]string{"collector", "device"},
+			nil,
+		),
+		durationDesc: prometheus.NewDesc(
+			"nvme_exporter_collector_duration_seconds",
+			"Duration of the collector for the device in seconds",
+			[]string{"collector", "device"},
+			nil,
+		),
+	}
 }
 
 // Describe calls Describe on every collector in cc.collectors.
@@ -221,19 +289,88 @@ func (cc *CompositeCollector) Describe(ch chan<- *prometheus.Desc) {
 	for _, collector := range cc.collectors {
 		collector.Describe(ch)
 	}
+
+	ch <- cc.successDesc
+	ch <- cc.durationDesc
 }
 
-// Collect calls Collect on every collector in cc.collectors.
+// Collect walks the subsystems topology and calls, on every collector in cc.collectors,
+// the collect method matching the scope of each subsystem, controller and namespace.
 func (cc *CompositeCollector) Collect(ch chan<- prometheus.Metric) {
-	devices := GetDevices()
+	subsystems := cc.getDevices()
+
+	for _, subsystem := range subsystems {
+		cc.collectSubsystem(ch, subsystem)
+	}
+}
 
-	for _, device := range devices {
+func (cc *CompositeCollector) collectSubsystem(ch chan<- prometheus.Metric, subsystem *Subsystem) {
+	for _, collector := range cc.collectors {
+		if subsystemCollector, ok := collector.MetricCollector.(SubsystemMetricCollector); ok {
+			cc.collectDevice(ch, collector.Name, subsystem.Name, func() error {
+				return subsystemCollector.CollectSubsystemMetrics(ch, subsystem)
+			})
+		}
+	}
+
+	for _, controller := range subsystem.Controllers {
+		cc.collectController(ch, controller)
+	}
+}
+
+func (cc *CompositeCollector) collectController(ch chan<- prometheus.Metric, controller *Controller) {
+	for _, collector := range cc.collectors {
+		if controllerCollector, ok := collector.MetricCollector.(ControllerMetricCollector); ok {
+			device := ""
+			if controller.Name != "" {
+				device = "/dev/" + controller.Name
+			}
+
+			cc.collectDevice(ch, collector.Name, device, func() error {
+				return controllerCollector.CollectControllerMetrics(ch, controller)
+			})
+		}
+	}
+
+	for _, namespace := range controller.Namespaces {
 		for _, collector := range cc.collectors {
-			collector.CollectMetrics(ch, device)
+			if namespaceCollector, ok := collector.MetricCollector.(NamespaceMetricCollector); ok {
+				cc.collectDevice(ch, collector.Name, namespace.DevicePath, func() error {
+					return namespaceCollector.CollectMetrics(ch, namespace)
+				})
+			}
 		}
 	}
 }
 
+// collectDevice runs the collect function of a collector for a device,
+// and sends the success and the duration of the collector for the device.
+// Devices without a name (e.g. the subsystems and controllers of the old flat nvme-cli
+// structure) cannot be told apart, so no metric is sent for them.
+func (cc *CompositeCollector) collectDevice(
+	ch chan<- prometheus.Metric,
+	collector string,
+	device string,
+	collect func() error,
+) {
+	start := time.Now()
+	err := collect()
+	duration := time.Since(start).Seconds()
+
+	if device == "" {
+		return
+	}
+
+	// The skipped commands are not failures
+	success := 1.0
+	if err != nil && !errors.Is(err, ErrSkipped) {
+		success = 0
+	}
+
+	ch <- prometheus.MustNewConstMetric(cc.successDesc, prometheus.GaugeValue, success, collector, device)
+	ch <- prometheus.MustNewConstMetric(cc.durationDesc, prometheus.GaugeValue, duration, collector, device)
+}
+
 // ValidationChecker allows injecting a validation check function
 // to determine if scrapes should proceed.
 type ValidationChecker func() bool
@@ -254,3 +391,19 @@ var scrapeFailureIncrementer ScrapeFailureIncrementer
 func SetScrapeFailureIncrementer(incrementer ScrapeFailureIncrementer) {
 	scrapeFailureIncrementer = incrementer
 }
+
+// checkValidation returns whether the scrape can proceed,
+// incrementing the scrape failures if it cannot.
+func checkValidation() bool {
+	if validationChecker != nil && !validationChecker() {
+		if scrapeFailureIncrementer != nil {
+			scrapeFailureIncrementer()
+		}
+
+		log.Printf("Skipping device query due to validation failure")
+
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,105 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+// newLogTestCollector returns a composite collector running the log collector
+// on the namespaces /dev/nvme0n1, /dev/nvme0n2 and /dev/nvme0n3.
+func newLogTestCollector(getData func(string) gjson.Result) *pkg.CompositeCollector {
+	subsystem := &pkg.Subsystem{Name: "nvme-subsys0"}
+	controller := &pkg.Controller{Subsystem: subsystem}
+	subsystem.Controllers = []*pkg.Controller{controller}
+
+	for _, name := range []string{"nvme0n1", "nvme0n2", "nvme0n3"} {
+		controller.Namespaces = append(controller.Namespaces, &pkg.Namespace{
+			Name:       name,
+			DevicePath: "/dev/" + name,
+			Controller: controller,
+		})
+	}
+
+	providers := []pkg.MetricProvider{
+		pkg.NewMetricProvider(
+			prometheus.NewDesc("nvme_test_value", "Test value", []string{"device"}, nil),
+			prometheus.GaugeValue,
+			"value",
+		),
+	}
+
+	return pkg.NewCompositeCollector(
+		[]pkg.NamedMetricCollector{
+			pkg.NewNamedMetricCollector("test", pkg.NewLogMetricCollector(providers, nil, getData)),
+		},
+		func() []*pkg.Subsystem {
+			return []*pkg.Subsystem{subsystem}
+		},
+	)
+}
+
+func TestCompositeCollectorSuccess(t *testing.T) {
+	t.Parallel()
+
+	collector := newLogTestCollector(func(devicePath string) gjson.Result {
+		switch devicePath {
+		case "/dev/nvme0n1":
+			return gjson.Parse(`{"value": 42}`)
+		case "/dev/nvme0n2":
+			return pkg.SkippedResult()
+		default:
+			// The command failed
+			return gjson.Result{}
+		}
+	})
+
+	values := gatherValues(t, collector)
+
+	checkValues(t, values, map[string]float64{
+		`nvme_test_value{device="/dev/nvme0n1"}`:                                  42,
+		`nvme_exporter_collector_success{collector="test",device="/dev/nvme0n1"}`: 1,
+		// The skipped commands are not failures
+		`nvme_exporter_collector_success{collector="test",device="/dev/nvme0n2"}`: 1,
+		`nvme_exporter_collector_success{collector="test",device="/dev/nvme0n3"}`: 0,
+	})
+}
+
+func TestCompositeCollectorTrackedCommand(t *testing.T) {
+	t.Parallel()
+
+	tracker := pkg.NewUnsupportedCommandTracker(nil)
+	runs := 0
+
+	collector := newLogTestCollector(tracker.Track("test-log", func(devicePath string) gjson.Result {
+		if devicePath != "/dev/nvme0n1" {
+			return pkg.SkippedResult()
+		}
+
+		runs++
+
+		return gjson.Result{}
+	}))
+
+	// The first collection runs the failing command, the second one skips it until its retry time
+	for _, success := range []float64{0, 1} {
+		values := gatherValues(t, collector)
+
+		checkValues(t, values, map[string]float64{
+			`nvme_exporter_collector_success{collector="test",device="/dev/nvme0n1"}`: success,
+			`nvme_exporter_collector_success{collector="test",device="/dev/nvme0n2"}`: 1,
+		})
+	}
+
+	if runs != 1 {
+		t.Errorf("the failed command ran %d times, want 1", runs)
+	}
+
+	// The commands skipped by the getter are not recorded as failing
+	if tracker.Skip("/dev/nvme0n2", "test-log") {
+		t.Error("the command skipped by the getter is recorded as failing")
+	}
+}
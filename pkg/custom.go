@@ -0,0 +1,287 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+	"go.yaml.in/yaml/v2"
+)
+
+// Scopes of the custom collectors.
+const (
+	CustomScopeNamespace  = "namespace"
+	CustomScopeController = "controller"
+)
+
+// metricNameRegex matches the valid Prometheus metric and label names.
+var metricNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// CustomConfig is the configuration file of the user-defined collectors.
+type CustomConfig struct {
+	Collectors []CustomCollectorConfig `yaml:"collectors"`
+}
+
+// CustomCollectorConfig declares a collector running a nvme subcommand
+// and mapping its JSON output onto metrics.
+type CustomCollectorConfig struct {
+	// Name identifies the collector in the logs
+	Name string `yaml:"name"`
+	// Command is the nvme subcommand plus arguments, the device path
+	// and the JSON output format are appended
+	Command []string `yaml:"command"`
+	// Scope is namespace (the command runs on each namespace block device)
+	// or controller (the command runs on each controller character device)
+	Scope string `yaml:"scope"`
+
+	Metrics []CustomMetricConfig `yaml:"metrics"`
+}
+
+// CustomMetricConfig maps a field of the command JSON output onto a metric.
+type CustomMetricConfig struct {
+	Name string `yaml:"name"`
+	Help string `yaml:"help"`
+	// Type is gauge (default) or counter
+	Type string `yaml:"type"`
+	// JSONPath is the gjson path of the metric value
+	JSONPath string `yaml:"json_path"`
+	// LabelsFrom maps extra label names to the gjson paths of their values
+	LabelsFrom map[string]string `yaml:"labels_from"`
+}
+
+// LoadCustomConfig reads and validates the configuration file of the user-defined collectors.
+func LoadCustomConfig(path string) (*CustomConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading custom collectors config %s: %w", path, err)
+	}
+
+	config := &CustomConfig{}
+	if err := yaml.UnmarshalStrict(content, config); err != nil {
+		return nil, fmt.Errorf("error parsing custom collectors config %s: %w", path, err)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid custom collectors config %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// validate checks the collectors configuration, the collector and metric names
+// must be unique since they identify the collector success and the metric series.
+func (config *CustomConfig) validate() error {
+	collectorNames := make(map[string]bool)
+	// metricCollectors maps the metric names to the collector declaring them
+	metricCollectors := make(map[string]string)
+
+	for i := range config.Collectors {
+		collector := &config.Collectors[i]
+
+		if err := collector.validate(); err != nil {
+			return err
+		}
+
+		if collectorNames[collector.Name] {
+			return fmt.Errorf("duplicate collector name %s", collector.Name)
+		}
+
+		collectorNames[collector.Name] = true
+
+		for _, metric := range collector.Metrics {
+			if other, found := metricCollectors[metric.Name]; found {
+				return fmt.Errorf("collector %s: metric %s is already declared by collector %s",
+					collector.Name, metric.Name, other)
+			}
+
+			metricCollectors[metric.Name] = collector.Name
+		}
+	}
+
+	return nil
+}
+
+// validate checks the collector configuration and fills in the defaults.
+func (cc *CustomCollectorConfig) validate() error {
+	if cc.Name == "" {
+		return fmt.Errorf("collector without name")
+	}
+
+	if len(cc.Command) == 0 {
+		return fmt.Errorf("collector %s: missing command", cc.Name)
+	}
+
+	if cc.Scope == "" {
+		cc.Scope = CustomScopeNamespace
+	}
+
+	if cc.Scope != CustomScopeNamespace && cc.Scope != CustomScopeController {
+		return fmt.Errorf("collector %s: unknown scope %q, supported scopes are %s and %s",
+			cc.Name, cc.Scope, CustomScopeNamespace, CustomScopeController)
+	}
+
+	for i := range cc.Metrics {
+		metric := &cc.Metrics[i]
+
+		if !metricNameRegex.MatchString(metric.Name) {
+			return fmt.Errorf("collector %s: invalid metric name %q", cc.Name, metric.Name)
+		}
+
+		if metric.JSONPath == "" {
+			return fmt.Errorf("collector %s: metric %s without json_path", cc.Name, metric.Name)
+		}
+
+		if metric.Type == "" {
+			metric.Type = "gauge"
+		}
+
+		if metric.Type != "gauge" && metric.Type != "counter" {
+			return fmt.Errorf("collector %s: metric %s has unknown type %q, supported types are gauge and counter",
+				cc.Name, metric.Name, metric.Type)
+		}
+
+		for label := range metric.LabelsFrom {
+			if !metricNameRegex.MatchString(label) {
+				return fmt.Errorf("collector %s: metric %s has invalid label name %q", cc.Name, metric.Name, label)
+			}
+
+			if label == cc.defaultLabel() {
+				return fmt.Errorf("collector %s: metric %s label %s is already set by the %s scope",
+					cc.Name, metric.Name, label, cc.Scope)
+			}
+		}
+	}
+
+	return nil
+}
+
+// defaultLabel returns the name of the label identifying the device of the collector scope.
+func (cc *CustomCollectorConfig) defaultLabel() string {
+	if cc.Scope == CustomScopeController {
+		return "controller"
+	}
+
+	return "device"
+}
+
+// customMetric is a MetricProvider with the paths of its extra label values.
+type customMetric struct {
+	provider   MetricProvider
+	labelPaths []string
+}
+
+// CustomMetricCollector implements NamespaceMetricCollector and ControllerMetricCollector,
+// and sends the metrics declared in a CustomCollectorConfig. Only the method
+// matching the configured scope sends metrics.
+type CustomMetricCollector struct {
+	scope   string
+	command []string
+	metrics []customMetric
+
+	// getData receives the command and the device path and gets the command JSON data
+	getData func(command []string, devicePath string) gjson.Result
+}
+
+// NewCustomMetricCollector initializes and returns a new CustomMetricCollector object.
+func NewCustomMetricCollector(
+	config CustomCollectorConfig,
+	getData func(command []string, devicePath string) gjson.Result,
+) *CustomMetricCollector {
+	defaultLabel := config.defaultLabel()
+
+	metrics := make([]customMetric, 0, len(config.Metrics))
+
+	for _, metricConfig := range config.Metrics {
+		// Label names are sorted to get a stable label order
+		labelNames := make([]string, 0, len(metricConfig.LabelsFrom))
+		for label := range metricConfig.LabelsFrom {
+			labelNames = append(labelNames, label)
+		}
+
+		sort.Strings(labelNames)
+
+		labelPaths := make([]string, 0, len(labelNames))
+		for _, label := range labelNames {
+			labelPaths = append(labelPaths, metricConfig.LabelsFrom[label])
+		}
+
+		valueType := prometheus.GaugeValue
+		if metricConfig.Type == "counter" {
+			valueType = prometheus.CounterValue
+		}
+
+		metrics = append(metrics, customMetric{
+			provider: NewOptionalMetricProvider(
+				prometheus.NewDesc(
+					metricConfig.Name,
+					metricConfig.Help,
+					append([]string{defaultLabel}, labelNames...),
+					nil,
+				),
+				valueType,
+				metricConfig.JSONPath,
+			),
+			labelPaths: labelPaths,
+		})
+	}
+
+	return &CustomMetricCollector{
+		scope:   config.Scope,
+		command: config.Command,
+		metrics: metrics,
+		getData: getData,
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (cc *CustomMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range cc.metrics {
+		ch <- metric.provider.Desc
+	}
+}
+
+// CollectMetrics runs the command on the namespace block device
+// and sends the metrics through the channel, if the scope is namespace.
+func (cc *CustomMetricCollector) CollectMetrics(ch chan<- prometheus.Metric, namespace *Namespace) error {
+	if cc.scope != CustomScopeNamespace {
+		return nil
+	}
+
+	return cc.collect(ch, namespace.DevicePath, namespace.DevicePath)
+}
+
+// CollectControllerMetrics runs the command on the controller character device
+// and sends the metrics through the channel, if the scope is controller.
+func (cc *CustomMetricCollector) CollectControllerMetrics(ch chan<- prometheus.Metric, controller *Controller) error {
+	if cc.scope != CustomScopeController || controller.Name == "" {
+		return nil
+	}
+
+	return cc.collect(ch, "/dev/"+controller.Name, controller.Name)
+}
+
+func (cc *CustomMetricCollector) collect(ch chan<- prometheus.Metric, devicePath string, defaultLabel string) error {
+	jsonData := cc.getData(cc.command, devicePath)
+
+	// If getData returns invalid data (e.g., command not supported), skip this collector
+	if !jsonData.Exists() {
+		return noDataError(devicePath, jsonData)
+	}
+
+	for _, metric := range cc.metrics {
+		labels := []string{defaultLabel}
+		for _, path := range metric.labelPaths {
+			labels = append(labels, jsonData.Get(path).String())
+		}
+
+		// Fetching the metric object is delegated to the provider
+		if result := metric.provider.GetMetric(jsonData, labels...); result != nil {
+			ch <- result
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package pkg_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+func TestLoadCustomConfigErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config string
+		// want is a substring of the expected error
+		want string
+	}{
+		{
+			name: "device label in namespace scope",
+			config: `
+collectors:
+  - name: stats
+    command: [smart-log]
+    metrics:
+      - {name: nvme_stats_value, json_path: value, labels_from: {device: path}}
+`,
+			want: "label device is already set",
+		},
+		{
+			name: "controller label in controller scope",
+			config: `
+collectors:
+  - name: stats
+    command: [smart-log]
+    scope: controller
+    metrics:
+      - {name: nvme_stats_value, json_path: value, labels_from: {controller: path}}
+`,
+			want: "label controller is already set",
+		},
+		{
+			name: "duplicate metric in a collector",
+			config: `
+collectors:
+  - name: stats
+    command: [smart-log]
+    metrics:
+      - {name: nvme_stats_value, json_path: value}
+      - {name: nvme_stats_value, json_path: other}
+`,
+			want: "metric nvme_stats_value is already declared by collector stats",
+		},
+		{
+			name: "duplicate metric in two collectors",
+			config: `
+collectors:
+  - name: stats
+    command: [smart-log]
+    metrics:
+      - {name: nvme_stats_value, json_path: value}
+  - name: other_stats
+    command: [smart-log]
+    metrics:
+      - {name: nvme_stats_value, json_path: value}
+`,
+			want: "metric nvme_stats_value is already declared by collector stats",
+		},
+		{
+			name: "duplicate collector",
+			config: `
+collectors:
+  - name: stats
+    command: [smart-log]
+  - name: stats
+    command: [error-log]
+`,
+			want: "duplicate collector name stats",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "custom.yml")
+
+			err := os.WriteFile(path, []byte(test.config), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = pkg.LoadCustomConfig(path)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("LoadCustomConfig() error = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadCustomConfigDefaultLabelOfOtherScope(t *testing.T) {
+	t.Parallel()
+
+	// The device label is only set by the namespace scope
+	path := filepath.Join(t.TempDir(), "custom.yml")
+
+	err := os.WriteFile(path, []byte(`
+collectors:
+  - name: stats
+    command: [smart-log]
+    scope: controller
+    metrics:
+      - {name: nvme_stats_value, json_path: value, labels_from: {device: path}}
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = pkg.LoadCustomConfig(path)
+	if err != nil {
+		t.Errorf("LoadCustomConfig() error = %v", err)
+	}
+}
@@ -0,0 +1,254 @@
+package pkg
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/tidwall/gjson"
+)
+
+// Subsystem is an NVMe subsystem, grouping the controllers
+// that give access to the same namespaces.
+type Subsystem struct {
+	// Name is the kernel name of the subsystem (e.g. nvme-subsys0),
+	// empty if the data source does not report it
+	Name string
+	// NQN is the NVMe Qualified Name of the subsystem
+	NQN string
+
+	Controllers []*Controller
+}
+
+// Controller is an NVMe controller of a subsystem.
+type Controller struct {
+	// Name is the kernel name of the controller (e.g. nvme0)
+	Name string
+	// Transport is the transport of the controller (e.g. pcie, tcp, rdma, fc, loop)
+	Transport string
+	// Address is the transport address of the controller
+	// (e.g. the PCI address, or traddr/trsvcid for fabrics)
+	Address string
+
+	SerialNumber string
+	ModelNumber  string
+	Firmware     string
+
+	// Namespaces are the namespaces attached to the controller.
+	// Multipath namespaces shared by several controllers are only
+	// attached to the first one, the others reach them through Paths
+	Namespaces []*Namespace
+
+	// Paths are the paths to the multipath namespaces through the controller
+	Paths []*Path
+
+	// Subsystem is the subsystem the controller belongs to
+	Subsystem *Subsystem
+}
+
+// Path is a path to a multipath namespace through a controller.
+type Path struct {
+	// Name is the kernel name of the path (e.g. nvme0c1n1)
+	Name string
+	// Namespace is the kernel name of the namespace head block device (e.g. nvme0n1)
+	Namespace string
+}
+
+// NamespaceCount returns the number of distinct namespaces the controller
+// gives access to, either directly or through multipath paths.
+func (c *Controller) NamespaceCount() int {
+	names := make(map[string]bool)
+
+	for _, namespace := range c.Namespaces {
+		names[namespace.Name] = true
+	}
+
+	for _, path := range c.Paths {
+		names[path.Namespace] = true
+	}
+
+	return len(names)
+}
+
+// newPath returns the Path for a path name (nvmeXcYnZ),
+// or nil if the name is not a multipath path.
+func newPath(name string) *Path {
+	match := namespaceBlockRegex.FindStringSubmatch(name)
+	if match == nil || match[2] == "" {
+		return nil
+	}
+
+	return &Path{
+		Name:      name,
+		Namespace: "nvme" + match[1] + "n" + match[3],
+	}
+}
+
+// Namespace is an NVMe namespace, as seen through its controller.
+type Namespace struct {
+	// Name is the kernel name of the namespace block device (e.g. nvme0n1)
+	Name string
+	// DevicePath is the path of the namespace block device (e.g. /dev/nvme0n1)
+	DevicePath string
+	// GenericPath is the namespace generic character device, empty if not available
+	GenericPath string
+
+	NSID int64
+
+	// The sizes are nil if the data source cannot report them (e.g. UsedBytes with the sysfs backend)
+	MaximumLBA   *int64
+	PhysicalSize *int64
+	SectorSize   *int64
+	UsedBytes    *int64
+
+	// Controller is the controller the namespace is attached to
+	Controller *Controller
+}
+
+// parseNestedDevices builds the topology from the nested nvme-cli JSON structure
+// (Devices -> Subsystems -> Controllers -> Namespaces).
+func parseNestedDevices(devices []gjson.Result) []*Subsystem {
+	var subsystems []*Subsystem
+
+	for _, device := range devices {
+		for _, subsystemJSON := range device.Get("Subsystems").Array() {
+			subsystem := &Subsystem{
+				Name: subsystemJSON.Get("Subsystem").String(),
+				NQN:  subsystemJSON.Get("SubsystemNQN").String(),
+			}
+
+			for _, controllerJSON := range subsystemJSON.Get("Controllers").Array() {
+				controller := &Controller{
+					Name:         controllerJSON.Get("Controller").String(),
+					Transport:    controllerJSON.Get("Transport").String(),
+					Address:      controllerJSON.Get("Address").String(),
+					SerialNumber: controllerJSON.Get("SerialNumber").String(),
+					ModelNumber:  controllerJSON.Get("ModelNumber").String(),
+					Firmware:     controllerJSON.Get("Firmware").String(),
+					Subsystem:    subsystem,
+				}
+
+				for _, namespaceJSON := range controllerJSON.Get("Namespaces").Array() {
+					controller.Namespaces = append(controller.Namespaces, parseNestedNamespace(namespaceJSON, controller))
+				}
+
+				for _, pathJSON := range controllerJSON.Get("Paths").Array() {
+					if path := newPath(pathJSON.Get("Path").String()); path != nil {
+						controller.Paths = append(controller.Paths, path)
+					}
+				}
+
+				subsystem.Controllers = append(subsystem.Controllers, controller)
+			}
+
+			// Multipath namespaces are reported at the subsystem level
+			for _, namespaceJSON := range subsystemJSON.Get("Namespaces").Array() {
+				controller := sharedNamespaceController(subsystem, namespaceJSON.Get("NameSpace").String())
+				if controller != nil {
+					controller.Namespaces = append(controller.Namespaces, parseNestedNamespace(namespaceJSON, controller))
+				}
+			}
+
+			subsystems = append(subsystems, subsystem)
+		}
+	}
+
+	return subsystems
+}
+
+// parseNestedNamespace builds a namespace from the nested nvme-cli JSON structure.
+func parseNestedNamespace(namespaceJSON gjson.Result, controller *Controller) *Namespace {
+	namespaceName := namespaceJSON.Get("NameSpace").String()
+
+	return &Namespace{
+		Name:         namespaceName,
+		DevicePath:   "/dev/" + namespaceName,
+		GenericPath:  namespaceJSON.Get("Generic").String(),
+		NSID:         namespaceJSON.Get("NSID").Int(),
+		UsedBytes:    optionalInt(namespaceJSON.Get("UsedBytes")),
+		MaximumLBA:   optionalInt(namespaceJSON.Get("MaximumLBA")),
+		PhysicalSize: optionalInt(namespaceJSON.Get("PhysicalSize")),
+		SectorSize:   optionalInt(namespaceJSON.Get("SectorSize")),
+		Controller:   controller,
+	}
+}
+
+// sharedNamespaceController returns the first controller of the subsystem
+// with a path to the multipath namespace, falling back to the first controller.
+func sharedNamespaceController(subsystem *Subsystem, namespaceName string) *Controller {
+	for _, controller := range subsystem.Controllers {
+		for _, path := range controller.Paths {
+			if path.Namespace == namespaceName {
+				return controller
+			}
+		}
+	}
+
+	if len(subsystem.Controllers) == 0 {
+		return nil
+	}
+
+	return subsystem.Controllers[0]
+}
+
+// parseFlatDevices builds the topology from the old flat nvme-cli JSON structure,
+// where each device is a namespace. The flat structure does not report
+// subsystems nor controllers, so the namespaces are grouped by the controller
+// name derived from the device path, each in its own anonymous subsystem.
+func parseFlatDevices(devices []gjson.Result) []*Subsystem {
+	controllers := make(map[string]*Controller)
+
+	for _, device := range devices {
+		devicePath := device.Get("DevicePath").String()
+		name := controllerNameRegex.FindString(filepath.Base(devicePath))
+
+		controller, found := controllers[name]
+		if !found {
+			controller = &Controller{
+				Name:         name,
+				SerialNumber: device.Get("SerialNumber").String(),
+				ModelNumber:  device.Get("ModelNumber").String(),
+				Firmware:     device.Get("Firmware").String(),
+				Subsystem:    &Subsystem{},
+			}
+			controller.Subsystem.Controllers = []*Controller{controller}
+			controllers[name] = controller
+		}
+
+		controller.Namespaces = append(controller.Namespaces, &Namespace{
+			Name:         filepath.Base(devicePath),
+			DevicePath:   devicePath,
+			GenericPath:  device.Get("GenericPath").String(),
+			NSID:         device.Get("NameSpace").Int(),
+			UsedBytes:    optionalInt(device.Get("UsedBytes")),
+			MaximumLBA:   optionalInt(device.Get("MaximumLBA")),
+			PhysicalSize: optionalInt(device.Get("PhysicalSize")),
+			SectorSize:   optionalInt(device.Get("SectorSize")),
+			Controller:   controller,
+		})
+	}
+
+	names := make([]string, 0, len(controllers))
+	for name := range controllers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	subsystems := make([]*Subsystem, 0, len(names))
+	for _, name := range names {
+		subsystems = append(subsystems, controllers[name].Subsystem)
+	}
+
+	return subsystems
+}
+
+// optionalInt returns the JSON integer, nil if the field is missing.
+func optionalInt(result gjson.Result) *int64 {
+	if !result.Exists() {
+		return nil
+	}
+
+	value := result.Int()
+
+	return &value
+}
@@ -0,0 +1,186 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+// readDevicesFixture returns the devices of a nvme list -o json fixture.
+func readDevicesFixture(t *testing.T, name string) []gjson.Result {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return gjson.ParseBytes(content).Get("Devices").Array()
+}
+
+// namespaceNames returns the names of the namespaces attached to the controller.
+func namespaceNames(controller *Controller) []string {
+	names := make([]string, 0, len(controller.Namespaces))
+	for _, namespace := range controller.Namespaces {
+		names = append(names, namespace.Name)
+	}
+
+	return names
+}
+
+// checkNames reports the names different from the expected ones.
+func checkNames(t *testing.T, what string, got []string, want []string) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Errorf("%s = %v, want %v", what, got, want)
+
+		return
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s = %v, want %v", what, got, want)
+
+			return
+		}
+	}
+}
+
+// checkSize reports a namespace size different from the expected one, nil meaning missing.
+func checkSize(t *testing.T, what string, got *int64, want *int64) {
+	t.Helper()
+
+	switch {
+	case got == nil && want == nil:
+	case got == nil || want == nil:
+		t.Errorf("%s = %v, want %v", what, got, want)
+	case *got != *want:
+		t.Errorf("%s = %d, want %d", what, *got, *want)
+	}
+}
+
+func size(value int64) *int64 {
+	return &value
+}
+
+func TestParseNestedDevices(t *testing.T) {
+	t.Parallel()
+
+	subsystems := parseNestedDevices(readDevicesFixture(t, "nvme-list-verbose.json"))
+	if len(subsystems) != 2 {
+		t.Fatalf("parseNestedDevices() returned %d subsystems, want 2", len(subsystems))
+	}
+
+	local := subsystems[0]
+	if local.Name != "nvme-subsys0" || len(local.Controllers) != 1 {
+		t.Fatalf("first subsystem = %s with %d controllers, want nvme-subsys0 with 1", local.Name, len(local.Controllers))
+	}
+
+	nvme0 := local.Controllers[0]
+	if nvme0.Name != "nvme0" || nvme0.Transport != "pcie" || nvme0.Address != "0000:c1:00.0" ||
+		nvme0.Firmware != "GDC5602Q" || nvme0.Subsystem != local {
+		t.Errorf("nvme0 = %+v", nvme0)
+	}
+
+	checkNames(t, "nvme0 namespaces", namespaceNames(nvme0), []string{"nvme0n1", "nvme0n2"})
+
+	nvme0n1 := nvme0.Namespaces[0]
+	if nvme0n1.DevicePath != "/dev/nvme0n1" || nvme0n1.GenericPath != "ng0n1" || nvme0n1.NSID != 1 ||
+		nvme0n1.Controller != nvme0 {
+		t.Errorf("nvme0n1 = %+v", nvme0n1)
+	}
+
+	checkSize(t, "nvme0n1 UsedBytes", nvme0n1.UsedBytes, size(183148240896))
+	checkSize(t, "nvme0n1 MaximumLBA", nvme0n1.MaximumLBA, size(3750748848))
+	checkSize(t, "nvme0n1 PhysicalSize", nvme0n1.PhysicalSize, size(1920383410176))
+	checkSize(t, "nvme0n1 SectorSize", nvme0n1.SectorSize, size(512))
+
+	// Missing sizes are nil, not zero
+	nvme0n2 := nvme0.Namespaces[1]
+	checkSize(t, "nvme0n2 UsedBytes", nvme0n2.UsedBytes, nil)
+	checkSize(t, "nvme0n2 MaximumLBA", nvme0n2.MaximumLBA, nil)
+	checkSize(t, "nvme0n2 PhysicalSize", nvme0n2.PhysicalSize, nil)
+	checkSize(t, "nvme0n2 SectorSize", nvme0n2.SectorSize, nil)
+
+	fabrics := subsystems[1]
+	if fabrics.NQN != "nqn.2019-10.com.vendor:target" || len(fabrics.Controllers) != 2 {
+		t.Fatalf("second subsystem = %s with %d controllers, want 2 controllers", fabrics.NQN, len(fabrics.Controllers))
+	}
+
+	nvme1, nvme2 := fabrics.Controllers[0], fabrics.Controllers[1]
+
+	// The multipath namespaces reported at the subsystem level are attached to the first
+	// controller with a path to them, or to the first controller if no path reaches them
+	checkNames(t, "nvme1 namespaces", namespaceNames(nvme1), []string{"nvme1n1", "nvme1n3"})
+	checkNames(t, "nvme2 namespaces", namespaceNames(nvme2), []string{"nvme1n2"})
+
+	if nvme2.Namespaces[0].Controller != nvme2 {
+		t.Error("nvme1n2 is not attached to nvme2")
+	}
+
+	// The paths are attached to their controller and name their namespace head
+	if len(nvme2.Paths) != 2 || nvme2.Paths[0].Name != "nvme1c2n1" || nvme2.Paths[0].Namespace != "nvme1n1" ||
+		nvme2.Paths[1].Name != "nvme1c2n2" || nvme2.Paths[1].Namespace != "nvme1n2" {
+		t.Errorf("nvme2 paths = %+v", nvme2.Paths)
+	}
+
+	if nvme1.NamespaceCount() != 2 || nvme2.NamespaceCount() != 2 {
+		t.Errorf("namespace counts = %d and %d, want 2 and 2", nvme1.NamespaceCount(), nvme2.NamespaceCount())
+	}
+}
+
+func TestParseFlatDevices(t *testing.T) {
+	t.Parallel()
+
+	subsystems := parseFlatDevices(readDevicesFixture(t, "nvme-list-flat.json"))
+	if len(subsystems) != 2 {
+		t.Fatalf("parseFlatDevices() returned %d subsystems, want 2", len(subsystems))
+	}
+
+	// The namespaces are grouped by the controller derived from the device path,
+	// each controller in its own anonymous subsystem
+	for i, want := range []struct {
+		controller string
+		firmware   string
+		namespaces []string
+	}{
+		{controller: "nvme0", firmware: "GDC5602Q", namespaces: []string{"nvme0n1", "nvme0n2"}},
+		{controller: "nvme1", firmware: "2B2QEXM7", namespaces: []string{"nvme1n1"}},
+	} {
+		subsystem := subsystems[i]
+		if subsystem.Name != "" || len(subsystem.Controllers) != 1 {
+			t.Fatalf("subsystem %d = %q with %d controllers, want an anonymous subsystem with 1",
+				i, subsystem.Name, len(subsystem.Controllers))
+		}
+
+		controller := subsystem.Controllers[0]
+		if controller.Name != want.controller || controller.Firmware != want.firmware || controller.Subsystem != subsystem {
+			t.Errorf("controller %d = %+v", i, controller)
+		}
+
+		checkNames(t, want.controller+" namespaces", namespaceNames(controller), want.namespaces)
+	}
+
+	nvme0 := subsystems[0].Controllers[0]
+
+	nvme0n1 := nvme0.Namespaces[0]
+	if nvme0n1.DevicePath != "/dev/nvme0n1" || nvme0n1.GenericPath != "/dev/ng0n1" || nvme0n1.NSID != 1 ||
+		nvme0n1.Controller != nvme0 {
+		t.Errorf("nvme0n1 = %+v", nvme0n1)
+	}
+
+	checkSize(t, "nvme0n1 UsedBytes", nvme0n1.UsedBytes, size(183148240896))
+	checkSize(t, "nvme0n1 SectorSize", nvme0n1.SectorSize, size(512))
+
+	// Missing sizes are nil, not zero
+	checkSize(t, "nvme0n2 UsedBytes", nvme0.Namespaces[1].UsedBytes, nil)
+	checkSize(t, "nvme0n2 MaximumLBA", nvme0.Namespaces[1].MaximumLBA, nil)
+
+	// nvme-cli 1.x does not report the generic path
+	if nvme1n1 := subsystems[1].Controllers[0].Namespaces[0]; nvme1n1.GenericPath != "" {
+		t.Errorf("nvme1n1 GenericPath = %q, want empty", nvme1n1.GenericPath)
+	}
+}
@@ -0,0 +1,90 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// EnduranceMetricCollector implements ControllerMetricCollector and sends
+// the Endurance Group Information log (09h) metrics of each endurance group
+// of the controller, enumerated up to the ENDGIDMAX field of Identify Controller.
+type EnduranceMetricCollector struct {
+	// LogMetricProviders is the list of providers for the endurance group log fields
+	LogMetricProviders []MetricProvider
+
+	// getIdentifyData receives the controller device path and gets the Identify Controller JSON data
+	getIdentifyData func(string) gjson.Result
+	// getData receives the controller device path and the endurance group identifier
+	// and gets the endurance group log JSON data
+	getData func(string, int64) gjson.Result
+}
+
+// NewEnduranceMetricCollector initializes and returns a new EnduranceMetricCollector object.
+func NewEnduranceMetricCollector(
+	providers []MetricProvider,
+	getIdentifyData func(string) gjson.Result,
+	getData func(string, int64) gjson.Result,
+) *EnduranceMetricCollector {
+	return &EnduranceMetricCollector{
+		LogMetricProviders: providers,
+		getIdentifyData:    getIdentifyData,
+		getData:            getData,
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (ec *EnduranceMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, logProvider := range ec.LogMetricProviders {
+		ch <- logProvider.Desc
+	}
+}
+
+// CollectControllerMetrics gets the endurance group log of each endurance group
+// of the controller and sends all its metrics through the channel.
+// The groups whose log cannot be read are reported together in the returned error.
+func (ec *EnduranceMetricCollector) CollectControllerMetrics(
+	ch chan<- prometheus.Metric,
+	controller *Controller,
+) error {
+	if controller.Name == "" {
+		return nil
+	}
+
+	devicePath := "/dev/" + controller.Name
+
+	identifyData := ec.getIdentifyData(devicePath)
+	if !identifyData.Exists() {
+		return noDataError(devicePath, identifyData)
+	}
+
+	// Controllers without endurance groups report an ENDGIDMAX of 0
+	maxGroupID := identifyData.Get("endgidmax").Int()
+
+	var groupErrors []error
+
+	for groupID := int64(1); groupID <= maxGroupID; groupID++ {
+		jsonData := ec.getData(devicePath, groupID)
+		if !jsonData.Exists() {
+			// The skipped groups are not failures
+			if !IsSkipped(jsonData) {
+				groupErrors = append(groupErrors, fmt.Errorf("endurance group %d: %w",
+					groupID, noDataError(devicePath, jsonData)))
+			}
+
+			continue
+		}
+
+		for _, logProvider := range ec.LogMetricProviders {
+			metric := logProvider.GetMetric(jsonData, controller.Name, strconv.FormatInt(groupID, 10))
+			if metric != nil {
+				ch <- metric
+			}
+		}
+	}
+
+	return errors.Join(groupErrors...)
+}
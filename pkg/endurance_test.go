@@ -0,0 +1,57 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+func TestEnduranceMetricCollector(t *testing.T) {
+	t.Parallel()
+
+	subsystem := &pkg.Subsystem{Name: "nvme-subsys0"}
+	subsystem.Controllers = []*pkg.Controller{
+		{Name: "nvme0", Subsystem: subsystem},
+		{Name: "nvme1", Subsystem: subsystem},
+	}
+
+	providers := []pkg.MetricProvider{
+		pkg.NewMetricProvider(
+			prometheus.NewDesc("nvme_endurance_group_percent_used", "Test value",
+				[]string{"controller", "endurance_group"}, nil),
+			prometheus.GaugeValue,
+			"percent_used",
+		),
+	}
+
+	getIdentifyData := func(string) gjson.Result {
+		return gjson.Parse(`{"endgidmax": 3}`)
+	}
+
+	getData := func(devicePath string, groupID int64) gjson.Result {
+		switch {
+		case groupID == 1:
+			return gjson.Parse(`{"percent_used": 7}`)
+		case groupID == 2 && devicePath == "/dev/nvme0":
+			// The command failed
+			return gjson.Result{}
+		default:
+			return pkg.SkippedResult()
+		}
+	}
+
+	collector := newTestCollector("endurance", pkg.NewEnduranceMetricCollector(providers, getIdentifyData, getData),
+		subsystem)
+
+	checkValues(t, gatherValues(t, collector), map[string]float64{
+		// The groups read before and after a failed group are exported
+		`nvme_endurance_group_percent_used{controller="nvme0",endurance_group="1"}`: 7,
+		`nvme_endurance_group_percent_used{controller="nvme1",endurance_group="1"}`: 7,
+		// A failed group log fails the collection, the skipped ones do not
+		`nvme_exporter_collector_success{collector="endurance",device="/dev/nvme0"}`: 0,
+		`nvme_exporter_collector_success{collector="endurance",device="/dev/nvme1"}`: 1,
+	})
+}
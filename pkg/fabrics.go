@@ -0,0 +1,201 @@
+package pkg
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg/utils"
+)
+
+// fabricsStateConnecting is the controller state while the host is reconnecting.
+const fabricsStateConnecting = "connecting"
+
+// fabricsTimeoutOff is the value reported by the timeout attributes when disabled.
+const fabricsTimeoutOff = "off"
+
+// fabricsStates are the controller states reported by the kernel in the state attribute.
+var fabricsStates = []string{"new", "live", "resetting", fabricsStateConnecting, "deleting", "deleting (noio)", "dead"}
+
+// FabricsMetricCollector implements ControllerMetricCollector and sends the
+// connection metrics of the NVMe over Fabrics controllers (tcp, rdma, fc, loop),
+// reading the controller sysfs attributes.
+type FabricsMetricCollector struct {
+	sysfsRoot string
+
+	infoDesc              *prometheus.Desc
+	stateDesc             *prometheus.Desc
+	ctrlLossTimeoutDesc   *prometheus.Desc
+	reconnectDelayDesc    *prometheus.Desc
+	fastIOFailTimeoutDesc *prometheus.Desc
+	reconnectsDesc        *prometheus.Desc
+
+	// mutex protects the fields below, since the registry may collect concurrently
+	mutex sync.Mutex
+	// lastStates holds the state of each controller at the previous collection
+	lastStates map[string]string
+	// reconnects holds the number of reconnections observed for each controller
+	reconnects map[string]float64
+}
+
+// NewFabricsMetricCollector initializes and returns a new FabricsMetricCollector object.
+func NewFabricsMetricCollector(sysfsRoot string) *FabricsMetricCollector {
+	labels := []string{"controller"}
+
+	return &FabricsMetricCollector{
+		sysfsRoot: sysfsRoot,
+		infoDesc: prometheus.NewDesc(
+			"nvme_fabrics_controller_info",
+			"NVMe over Fabrics controller connection information, value is always 1",
+			[]string{"controller", "transport", "traddr", "trsvcid", "host_traddr", "hostnqn", "subsysnqn"},
+			nil,
+		),
+		stateDesc: prometheus.NewDesc(
+			"nvme_fabrics_controller_state",
+			"NVMe over Fabrics controller state, 1 for the current state and 0 for the others",
+			[]string{"controller", "state"},
+			nil,
+		),
+		ctrlLossTimeoutDesc: prometheus.NewDesc(
+			"nvme_fabrics_ctrl_loss_tmo_seconds",
+			"Time after which the host gives up reconnecting to a lost controller, -1 if reconnecting forever",
+			labels,
+			nil,
+		),
+		reconnectDelayDesc: prometheus.NewDesc(
+			"nvme_fabrics_reconnect_delay_seconds",
+			"Delay between reconnection attempts to a lost controller",
+			labels,
+			nil,
+		),
+		fastIOFailTimeoutDesc: prometheus.NewDesc(
+			"nvme_fabrics_fast_io_fail_tmo_seconds",
+			"Time after which I/O to a lost controller is failed, -1 if disabled",
+			labels,
+			nil,
+		),
+		reconnectsDesc: prometheus.NewDesc(
+			"nvme_fabrics_reconnects_total",
+			"Number of times the controller was observed entering the connecting state since the exporter started",
+			labels,
+			nil,
+		),
+		lastStates: make(map[string]string),
+		reconnects: make(map[string]float64),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (fc *FabricsMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- fc.infoDesc
+	ch <- fc.stateDesc
+	ch <- fc.ctrlLossTimeoutDesc
+	ch <- fc.reconnectDelayDesc
+	ch <- fc.fastIOFailTimeoutDesc
+	ch <- fc.reconnectsDesc
+}
+
+// CollectControllerMetrics reads the fabrics attributes of the controller
+// and sends the connection metrics through the channel.
+// PCIe controllers are skipped.
+func (fc *FabricsMetricCollector) CollectControllerMetrics(ch chan<- prometheus.Metric, controller *Controller) error {
+	controllerDir := filepath.Join(fc.sysfsRoot, "class", "nvme", controller.Name)
+
+	transport := readSysfsStringOrEmpty(filepath.Join(controllerDir, "transport"))
+	if transport == "" || transport == "pcie" {
+		return nil
+	}
+
+	address := parseFabricsAddress(readSysfsStringOrEmpty(filepath.Join(controllerDir, "address")))
+
+	ch <- prometheus.MustNewConstMetric(
+		fc.infoDesc,
+		prometheus.GaugeValue,
+		1,
+		controller.Name,
+		transport,
+		address["traddr"],
+		address["trsvcid"],
+		address["host_traddr"],
+		readSysfsStringOrEmpty(filepath.Join(controllerDir, "hostnqn")),
+		readSysfsStringOrEmpty(filepath.Join(controllerDir, "subsysnqn")),
+	)
+
+	state := readSysfsStringOrEmpty(filepath.Join(controllerDir, "state"))
+	sendStateSet(ch, fc.stateDesc, fabricsStates, state, controller.Name)
+
+	fc.sendTimeout(ch, fc.ctrlLossTimeoutDesc, filepath.Join(controllerDir, "ctrl_loss_tmo"), controller.Name)
+	fc.sendTimeout(ch, fc.reconnectDelayDesc, filepath.Join(controllerDir, "reconnect_delay"), controller.Name)
+	fc.sendTimeout(ch, fc.fastIOFailTimeoutDesc, filepath.Join(controllerDir, "fast_io_fail_tmo"), controller.Name)
+
+	ch <- prometheus.MustNewConstMetric(
+		fc.reconnectsDesc,
+		prometheus.CounterValue,
+		fc.trackReconnects(controller.Name, state),
+		controller.Name,
+	)
+
+	return nil
+}
+
+// trackReconnects updates the reconnections counter of the controller,
+// incrementing it when the controller enters the connecting state, and returns it.
+// Reconnections completed between two collections cannot be observed.
+func (fc *FabricsMetricCollector) trackReconnects(controller string, state string) float64 {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	lastState, found := fc.lastStates[controller]
+	if found && lastState != fabricsStateConnecting && state == fabricsStateConnecting {
+		fc.reconnects[controller]++
+	}
+
+	fc.lastStates[controller] = state
+
+	return fc.reconnects[controller]
+}
+
+// sendTimeout reads a timeout attribute in seconds and sends it through the channel.
+// Disabled timeouts ("off") are reported as -1, missing attributes are skipped.
+func (fc *FabricsMetricCollector) sendTimeout(
+	ch chan<- prometheus.Metric,
+	desc *prometheus.Desc,
+	path string,
+	controller string,
+) {
+	content, err := utils.ReadSysfsString(path)
+	if err != nil {
+		return
+	}
+
+	value := -1.0
+
+	if content != fabricsTimeoutOff {
+		seconds, err := strconv.ParseInt(content, 10, 64)
+		if err != nil {
+			return
+		}
+
+		value = float64(seconds)
+	}
+
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, controller)
+}
+
+// parseFabricsAddress parses the address attribute of a fabrics controller
+// (e.g. traddr=192.168.1.10,trsvcid=4420,src_addr=192.168.1.1) into its fields.
+func parseFabricsAddress(address string) map[string]string {
+	fields := make(map[string]string)
+
+	for _, field := range strings.Split(address, ",") {
+		key, value, found := strings.Cut(field, "=")
+		if found {
+			fields[key] = value
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,203 @@
+package pkg_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+// writeSysfsAttributes writes the attributes of a fake sysfs directory.
+func writeSysfsAttributes(t *testing.T, dir string, attributes map[string]string) {
+	t.Helper()
+
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, value := range attributes {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(value+"\n"), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// gatherValues collects the metrics of the collector and returns their values
+// keyed by name and labels, e.g. nvme_fabrics_reconnects_total{controller="nvme1"}.
+func gatherValues(t *testing.T, collector prometheus.Collector) map[string]float64 {
+	t.Helper()
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := make(map[string]float64)
+
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := make([]string, 0, len(metric.GetLabel()))
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, label.GetName()+`="`+label.GetValue()+`"`)
+			}
+
+			value := metric.GetGauge().GetValue()
+			if metric.GetCounter() != nil {
+				value = metric.GetCounter().GetValue()
+			}
+
+			values[family.GetName()+"{"+strings.Join(labels, ",")+"}"] = value
+		}
+	}
+
+	return values
+}
+
+// checkValues reports the expected values missing or different from the gathered ones.
+func checkValues(t *testing.T, values map[string]float64, expected map[string]float64) {
+	t.Helper()
+
+	for key, want := range expected {
+		got, found := values[key]
+		if !found {
+			t.Errorf("%s is missing", key)
+		} else if got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+}
+
+// newFabricsTestCollector returns the fabrics collector of a fake sysfs tree with
+// a tcp controller (nvme1), an rdma controller (nvme2) and a pcie controller (nvme0).
+func newFabricsTestCollector(t *testing.T) (*pkg.CompositeCollector, string) {
+	t.Helper()
+
+	sysfsRoot := t.TempDir()
+	classDir := filepath.Join(sysfsRoot, "class", "nvme")
+
+	writeSysfsAttributes(t, filepath.Join(classDir, "nvme0"), map[string]string{
+		"transport": "pcie",
+		"address":   "0000:01:00.0",
+		"state":     "live",
+	})
+	writeSysfsAttributes(t, filepath.Join(classDir, "nvme1"), map[string]string{
+		"transport":        "tcp",
+		"address":          "traddr=192.168.1.10,trsvcid=4420,src_addr=192.168.1.1",
+		"hostnqn":          "nqn.2014-08.org.nvmexpress:uuid:host",
+		"subsysnqn":        "nqn.2019-10.com.vendor:target",
+		"state":            "live",
+		"ctrl_loss_tmo":    "off",
+		"reconnect_delay":  "10",
+		"fast_io_fail_tmo": "off",
+	})
+	writeSysfsAttributes(t, filepath.Join(classDir, "nvme2"), map[string]string{
+		"transport":       "rdma",
+		"address":         "traddr=10.0.0.2,trsvcid=4420,host_traddr=10.0.0.1",
+		"hostnqn":         "nqn.2014-08.org.nvmexpress:uuid:host",
+		"subsysnqn":       "nqn.2019-10.com.vendor:target",
+		"state":           "live",
+		"ctrl_loss_tmo":   "600",
+		"reconnect_delay": "10",
+	})
+
+	subsystem := &pkg.Subsystem{Name: "nvme-subsys0"}
+	for _, name := range []string{"nvme0", "nvme1", "nvme2"} {
+		subsystem.Controllers = append(subsystem.Controllers, &pkg.Controller{Name: name, Subsystem: subsystem})
+	}
+
+	collector := pkg.NewCompositeCollector(
+		[]pkg.NamedMetricCollector{
+			pkg.NewNamedMetricCollector("fabrics", pkg.NewFabricsMetricCollector(sysfsRoot)),
+		},
+		func() []*pkg.Subsystem {
+			return []*pkg.Subsystem{subsystem}
+		},
+	)
+
+	return collector, classDir
+}
+
+func TestFabricsMetricCollectorConnection(t *testing.T) {
+	t.Parallel()
+
+	collector, _ := newFabricsTestCollector(t)
+	values := gatherValues(t, collector)
+
+	checkValues(t, values, map[string]float64{
+		`nvme_fabrics_controller_info{controller="nvme1",host_traddr="",` +
+			`hostnqn="nqn.2014-08.org.nvmexpress:uuid:host",subsysnqn="nqn.2019-10.com.vendor:target",` +
+			`traddr="192.168.1.10",transport="tcp",trsvcid="4420"}`: 1,
+		`nvme_fabrics_controller_info{controller="nvme2",host_traddr="10.0.0.1",` +
+			`hostnqn="nqn.2014-08.org.nvmexpress:uuid:host",subsysnqn="nqn.2019-10.com.vendor:target",` +
+			`traddr="10.0.0.2",transport="rdma",trsvcid="4420"}`: 1,
+		// Disabled timeouts are reported as -1
+		`nvme_fabrics_ctrl_loss_tmo_seconds{controller="nvme1"}`:    -1,
+		`nvme_fabrics_ctrl_loss_tmo_seconds{controller="nvme2"}`:    600,
+		`nvme_fabrics_fast_io_fail_tmo_seconds{controller="nvme1"}`: -1,
+		`nvme_fabrics_reconnect_delay_seconds{controller="nvme1"}`:  10,
+		`nvme_fabrics_reconnect_delay_seconds{controller="nvme2"}`:  10,
+	})
+
+	// Missing attributes are skipped
+	if _, found := values[`nvme_fabrics_fast_io_fail_tmo_seconds{controller="nvme2"}`]; found {
+		t.Error("fast_io_fail_tmo of nvme2 is missing but exported")
+	}
+
+	// PCIe controllers are skipped
+	for key := range values {
+		if strings.HasPrefix(key, "nvme_fabrics_") && strings.Contains(key, `controller="nvme0"`) {
+			t.Errorf("%s exported for a pcie controller", key)
+		}
+	}
+}
+
+func TestFabricsMetricCollectorReconnects(t *testing.T) {
+	t.Parallel()
+
+	collector, classDir := newFabricsTestCollector(t)
+
+	// Each step sets the state of nvme1 and checks its state and reconnections counter
+	steps := []struct {
+		state      string
+		reconnects float64
+	}{
+		// The first collection only records the state
+		{state: "connecting", reconnects: 0},
+		{state: "live", reconnects: 0},
+		{state: "connecting", reconnects: 1},
+		// Staying in the connecting state is the same reconnection
+		{state: "connecting", reconnects: 1},
+		{state: "resetting", reconnects: 1},
+		{state: "connecting", reconnects: 2},
+		{state: "live", reconnects: 2},
+	}
+
+	for _, step := range steps {
+		writeSysfsAttributes(t, filepath.Join(classDir, "nvme1"), map[string]string{"state": step.state})
+
+		values := gatherValues(t, collector)
+
+		checkValues(t, values, map[string]float64{
+			`nvme_fabrics_reconnects_total{controller="nvme1"}`:                            step.reconnects,
+			`nvme_fabrics_reconnects_total{controller="nvme2"}`:                            0,
+			`nvme_fabrics_controller_state{controller="nvme1",state="` + step.state + `"}`: 1,
+			`nvme_fabrics_controller_state{controller="nvme2",state="live"}`:               1,
+		})
+
+		for key, value := range values {
+			if strings.HasPrefix(key, `nvme_fabrics_controller_state{controller="nvme1"`) &&
+				!strings.HasSuffix(key, `state="`+step.state+`"}`) && value != 0 {
+				t.Errorf("%s = %v in the %s state, want 0", key, value, step.state)
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package pkg
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// fdpSupportedBit is the Flexible Data Placement Supported bit of the CTRATT field of Identify Controller.
+const fdpSupportedBit = 1 << 19
+
+// fdpHandleAttributes maps the Reclaim Unit Handle Attributes of the FDP usage log
+// to the attribute label values.
+var fdpHandleAttributes = map[int64]string{
+	0: "unused",
+	1: "host_specified",
+	2: "controller_specified",
+}
+
+// FdpMetricCollector implements ControllerMetricCollector and sends the Flexible Data Placement
+// metrics of each endurance group of the FDP capable controllers: the FDP statistics (host and
+// media bytes written, media bytes erased), the reclaim unit handle usage and the FDP configurations.
+type FdpMetricCollector struct {
+	// LogMetricProviders is the list of providers for the FDP statistics log fields
+	LogMetricProviders []MetricProvider
+
+	// getIdentifyData receives the controller device path and gets the Identify Controller JSON data
+	getIdentifyData func(string) gjson.Result
+	// getData receives the nvme fdp subcommand (configs, usage or stats), the controller device path
+	// and the endurance group identifier and gets the FDP log JSON data
+	getData func(string, string, int64) gjson.Result
+
+	handlesDesc         *prometheus.Desc
+	reclaimGroupsDesc   *prometheus.Desc
+	reclaimUnitSizeDesc *prometheus.Desc
+	configHandlesDesc   *prometheus.Desc
+}
+
+// NewFdpMetricCollector initializes and returns a new FdpMetricCollector object.
+func NewFdpMetricCollector(
+	providers []MetricProvider,
+	getIdentifyData func(string) gjson.Result,
+	getData func(string, string, int64) gjson.Result,
+) *FdpMetricCollector {
+	configLabels := []string{"controller", "endurance_group", "config"}
+
+	return &FdpMetricCollector{
+		LogMetricProviders: providers,
+		getIdentifyData:    getIdentifyData,
+		getData:            getData,
+		handlesDesc: prometheus.NewDesc(
+			"nvme_fdp_reclaim_unit_handles",
+			"Number of reclaim unit handles of the endurance group by attribute, from the FDP usage log",
+			[]string{"controller", "endurance_group", "attribute"},
+			nil,
+		),
+		reclaimGroupsDesc: prometheus.NewDesc(
+			"nvme_fdp_config_reclaim_groups",
+			"Number of reclaim groups of the FDP configuration",
+			configLabels,
+			nil,
+		),
+		reclaimUnitSizeDesc: prometheus.NewDesc(
+			"nvme_fdp_config_reclaim_unit_nominal_size_bytes",
+			"Nominal size of the reclaim units of the FDP configuration in bytes",
+			configLabels,
+			nil,
+		),
+		configHandlesDesc: prometheus.NewDesc(
+			"nvme_fdp_config_reclaim_unit_handles",
+			"Number of reclaim unit handles of the FDP configuration",
+			configLabels,
+			nil,
+		),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (fc *FdpMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, logProvider := range fc.LogMetricProviders {
+		ch <- logProvider.Desc
+	}
+
+	ch <- fc.handlesDesc
+	ch <- fc.reclaimGroupsDesc
+	ch <- fc.reclaimUnitSizeDesc
+	ch <- fc.configHandlesDesc
+}
+
+// CollectControllerMetrics gets the FDP logs of each endurance group
+// of the controller and sends all their metrics through the channel.
+func (fc *FdpMetricCollector) CollectControllerMetrics(ch chan<- prometheus.Metric, controller *Controller) error {
+	if controller.Name == "" {
+		return nil
+	}
+
+	devicePath := "/dev/" + controller.Name
+
+	identifyData := fc.getIdentifyData(devicePath)
+	if !identifyData.Exists() {
+		return noDataError(devicePath, identifyData)
+	}
+
+	if identifyData.Get("ctratt").Uint()&fdpSupportedBit == 0 {
+		return nil
+	}
+
+	maxGroupID := identifyData.Get("endgidmax").Int()
+
+	for groupID := int64(1); groupID <= maxGroupID; groupID++ {
+		group := strconv.FormatInt(groupID, 10)
+
+		statsData := fc.getData("stats", devicePath, groupID)
+		for _, logProvider := range fc.LogMetricProviders {
+			metric := logProvider.GetMetric(statsData, controller.Name, group)
+			if metric != nil {
+				ch <- metric
+			}
+		}
+
+		fc.collectUsage(ch, fc.getData("usage", devicePath, groupID), controller.Name, group)
+		fc.collectConfigs(ch, fc.getData("configs", devicePath, groupID), controller.Name, group)
+	}
+
+	return nil
+}
+
+// collectUsage counts the reclaim unit handles of the FDP usage log by attribute.
+func (fc *FdpMetricCollector) collectUsage(ch chan<- prometheus.Metric, usageData gjson.Result, labels ...string) {
+	if !usageData.Exists() {
+		return
+	}
+
+	counts := make(map[string]float64)
+	for _, attribute := range fdpHandleAttributes {
+		counts[attribute] = 0
+	}
+
+	for _, handle := range usageData.Get("ruhus").Array() {
+		if attribute, found := fdpHandleAttributes[handle.Get("ruha").Int()]; found {
+			counts[attribute]++
+		}
+	}
+
+	for attribute, count := range counts {
+		ch <- prometheus.MustNewConstMetric(
+			fc.handlesDesc,
+			prometheus.GaugeValue,
+			count,
+			append(labels, attribute)...,
+		)
+	}
+}
+
+// collectConfigs sends the size of each configuration of the FDP configurations log.
+func (fc *FdpMetricCollector) collectConfigs(ch chan<- prometheus.Metric, configsData gjson.Result, labels ...string) {
+	if !configsData.Exists() {
+		return
+	}
+
+	for index, config := range configsData.Get("configs").Array() {
+		configLabels := append(labels, strconv.Itoa(index))
+
+		ch <- prometheus.MustNewConstMetric(
+			fc.reclaimGroupsDesc,
+			prometheus.GaugeValue,
+			config.Get("nrg").Float(),
+			configLabels...,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			fc.reclaimUnitSizeDesc,
+			prometheus.GaugeValue,
+			config.Get("runs").Float(),
+			configLabels...,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			fc.configHandlesDesc,
+			prometheus.GaugeValue,
+			config.Get("nruh").Float(),
+			configLabels...,
+		)
+	}
+}
@@ -0,0 +1,76 @@
+package pkg_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+func TestFdpMetricCollector(t *testing.T) {
+	t.Parallel()
+
+	subsystem := &pkg.Subsystem{Name: "nvme-subsys0"}
+	subsystem.Controllers = []*pkg.Controller{
+		{Name: "nvme0", Subsystem: subsystem},
+		{Name: "nvme1", Subsystem: subsystem},
+	}
+
+	providers := []pkg.MetricProvider{
+		pkg.NewMetricProvider(
+			prometheus.NewDesc("nvme_fdp_host_bytes_written", "Test value",
+				[]string{"controller", "endurance_group"}, nil),
+			prometheus.CounterValue,
+			"hbmw",
+		),
+	}
+
+	getIdentifyData := func(devicePath string) gjson.Result {
+		if devicePath == "/dev/nvme1" {
+			// FDP not supported
+			return gjson.Parse(`{"ctratt": 0, "endgidmax": 1}`)
+		}
+
+		// FDP supported (bit 19 of CTRATT)
+		return gjson.Parse(`{"ctratt": 524288, "endgidmax": 1}`)
+	}
+
+	var calls []string
+
+	getData := func(subcommand string, devicePath string, _ int64) gjson.Result {
+		calls = append(calls, subcommand+" "+devicePath)
+
+		switch subcommand {
+		case "stats":
+			return gjson.Parse(`{"hbmw": 4096, "mbmw": 8192, "mbe": 0}`)
+		case "usage":
+			return gjson.Parse(`{"nruh": 4, "ruhus": [{"ruha": 1}, {"ruha": 1}, {"ruha": 2}, {"ruha": 0}]}`)
+		default:
+			return gjson.Parse(`{"configs": [{"nrg": 1, "nruh": 4, "runs": 1073741824}]}`)
+		}
+	}
+
+	collector := newTestCollector("fdp", pkg.NewFdpMetricCollector(providers, getIdentifyData, getData), subsystem)
+
+	values := gatherValues(t, collector)
+
+	checkValues(t, values, map[string]float64{
+		`nvme_fdp_host_bytes_written{controller="nvme0",endurance_group="1"}`:                                    4096,
+		`nvme_fdp_reclaim_unit_handles{attribute="host_specified",controller="nvme0",endurance_group="1"}`:       2,
+		`nvme_fdp_reclaim_unit_handles{attribute="controller_specified",controller="nvme0",endurance_group="1"}`: 1,
+		`nvme_fdp_reclaim_unit_handles{attribute="unused",controller="nvme0",endurance_group="1"}`:               1,
+		`nvme_fdp_config_reclaim_groups{config="0",controller="nvme0",endurance_group="1"}`:                      1,
+		`nvme_fdp_config_reclaim_unit_nominal_size_bytes{config="0",controller="nvme0",endurance_group="1"}`:     1 << 30,
+		`nvme_fdp_config_reclaim_unit_handles{config="0",controller="nvme0",endurance_group="1"}`:                4,
+	})
+
+	// The FDP logs are not read on the controllers without FDP support
+	for _, call := range calls {
+		if strings.HasSuffix(call, " /dev/nvme1") {
+			t.Errorf("nvme fdp %s ran on a controller without FDP support", call)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package pkg
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// Feature identifiers read by the features collector.
+const (
+	featureTemperatureThreshold = 0x04
+	featureVolatileWriteCache   = 0x06
+	featureNumberOfQueues       = 0x07
+	featureInterruptCoalescing  = 0x08
+	featureHostMemoryBuffer     = 0x0d
+	featureKeepAliveTimer       = 0x0f
+)
+
+const (
+	// temperatureSensors is the number of temperature sensors besides the composite temperature.
+	temperatureSensors = 8
+	// temperatureThresholdUnderBit is the THSEL value of CDW11 selecting the under temperature threshold.
+	temperatureThresholdUnderBit = 1 << 20
+	// temperatureSensorShift is the position of the TMPSEL field in CDW11.
+	temperatureSensorShift = 16
+	// kelvinOffset converts the temperatures in Kelvin to Celsius, with the integer offset used by nvme-cli.
+	kelvinOffset = 273
+	// interruptCoalescingTimeUnit is the unit of the aggregation time, in seconds.
+	interruptCoalescingTimeUnit = 0.0001
+	// keepAliveTimeoutUnit is the unit of the keep alive timeout, in seconds.
+	keepAliveTimeoutUnit = 0.001
+)
+
+// FeaturesMetricCollector implements ControllerMetricCollector and sends the current values
+// of the commonly tuned features of the controller: the temperature thresholds, the volatile
+// write cache, the number of queues, the interrupt coalescing, the host memory buffer and the
+// keep alive timer. The features the controller does not support are skipped.
+// The temperature sensors of each controller are found once in the SMART log and cached.
+type FeaturesMetricCollector struct {
+	// getSmartData receives the controller device path and gets the SMART log JSON data,
+	// used to find the temperature sensors implemented by the controller
+	getSmartData func(string) gjson.Result
+	// getFeatureData receives the controller device path, the feature identifier
+	// and the command dword 11 and gets the current feature value JSON data
+	getFeatureData func(string, int, uint32) gjson.Result
+
+	temperatureThresholdDesc *prometheus.Desc
+	writeCacheDesc           *prometheus.Desc
+	submissionQueuesDesc     *prometheus.Desc
+	completionQueuesDesc     *prometheus.Desc
+	coalescingThresholdDesc  *prometheus.Desc
+	coalescingTimeDesc       *prometheus.Desc
+	hostMemoryBufferDesc     *prometheus.Desc
+	keepAliveTimeoutDesc     *prometheus.Desc
+
+	// mutex protects the field below, since the registry may collect concurrently
+	mutex sync.Mutex
+	// sensors holds the temperature sensor labels of each controller device path, keyed by sensor
+	sensors map[string]map[uint32]string
+}
+
+// NewFeaturesMetricCollector initializes and returns a new FeaturesMetricCollector object.
+func NewFeaturesMetricCollector(
+	getSmartData func(string) gjson.Result,
+	getFeatureData func(string, int, uint32) gjson.Result,
+) *FeaturesMetricCollector {
+	labels := []string{"controller"}
+
+	return &FeaturesMetricCollector{
+		getSmartData:   getSmartData,
+		getFeatureData: getFeatureData,
+		temperatureThresholdDesc: prometheus.NewDesc(
+			"nvme_feature_temperature_threshold_celsius",
+			"Temperature threshold of the sensor in Celsius, by threshold type (over or under)",
+			[]string{"controller", "sensor", "threshold"},
+			nil,
+		),
+		writeCacheDesc: prometheus.NewDesc(
+			"nvme_feature_volatile_write_cache_enabled",
+			"Whether the volatile write cache is enabled (1 = enabled)",
+			labels,
+			nil,
+		),
+		submissionQueuesDesc: prometheus.NewDesc(
+			"nvme_feature_submission_queues",
+			"Number of I/O submission queues allocated by the controller",
+			labels,
+			nil,
+		),
+		completionQueuesDesc: prometheus.NewDesc(
+			"nvme_feature_completion_queues",
+			"Number of I/O completion queues allocated by the controller",
+			labels,
+			nil,
+		),
+		coalescingThresholdDesc: prometheus.NewDesc(
+			"nvme_feature_interrupt_coalescing_threshold",
+			"Minimum number of completion queue entries to aggregate per interrupt vector",
+			labels,
+			nil,
+		),
+		coalescingTimeDesc: prometheus.NewDesc(
+			"nvme_feature_interrupt_coalescing_time_seconds",
+			"Maximum time the controller delays an interrupt to aggregate completion queue entries",
+			labels,
+			nil,
+		),
+		hostMemoryBufferDesc: prometheus.NewDesc(
+			"nvme_feature_host_memory_buffer_enabled",
+			"Whether the host memory buffer is enabled (1 = enabled)",
+			labels,
+			nil,
+		),
+		keepAliveTimeoutDesc: prometheus.NewDesc(
+			"nvme_feature_keep_alive_timeout_seconds",
+			"Keep alive timeout of the controller, 0 if the keep alive timer is disabled",
+			labels,
+			nil,
+		),
+		sensors: make(map[string]map[uint32]string),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (fc *FeaturesMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- fc.temperatureThresholdDesc
+	ch <- fc.writeCacheDesc
+	ch <- fc.submissionQueuesDesc
+	ch <- fc.completionQueuesDesc
+	ch <- fc.coalescingThresholdDesc
+	ch <- fc.coalescingTimeDesc
+	ch <- fc.hostMemoryBufferDesc
+	ch <- fc.keepAliveTimeoutDesc
+}
+
+// CollectControllerMetrics gets the features of the controller and sends their values through the channel.
+func (fc *FeaturesMetricCollector) CollectControllerMetrics(ch chan<- prometheus.Metric, controller *Controller) error {
+	if controller.Name == "" {
+		return nil
+	}
+
+	devicePath := "/dev/" + controller.Name
+
+	fc.collectTemperatureThresholds(ch, devicePath, controller.Name)
+
+	if value, found := featureCurrentValue(fc.getFeatureData(devicePath, featureVolatileWriteCache, 0)); found {
+		ch <- prometheus.MustNewConstMetric(fc.writeCacheDesc, prometheus.GaugeValue, float64(value&1), controller.Name)
+	}
+
+	// The number of queues are 0's based
+	if value, found := featureCurrentValue(fc.getFeatureData(devicePath, featureNumberOfQueues, 0)); found {
+		ch <- prometheus.MustNewConstMetric(
+			fc.submissionQueuesDesc, prometheus.GaugeValue, float64(value&0xffff+1), controller.Name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			fc.completionQueuesDesc, prometheus.GaugeValue, float64(value>>16&0xffff+1), controller.Name,
+		)
+	}
+
+	// The aggregation threshold is 0's based
+	if value, found := featureCurrentValue(fc.getFeatureData(devicePath, featureInterruptCoalescing, 0)); found {
+		ch <- prometheus.MustNewConstMetric(
+			fc.coalescingThresholdDesc, prometheus.GaugeValue, float64(value&0xff+1), controller.Name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			fc.coalescingTimeDesc,
+			prometheus.GaugeValue,
+			float64(value>>8&0xff)*interruptCoalescingTimeUnit,
+			controller.Name,
+		)
+	}
+
+	if value, found := featureCurrentValue(fc.getFeatureData(devicePath, featureHostMemoryBuffer, 0)); found {
+		ch <- prometheus.MustNewConstMetric(
+			fc.hostMemoryBufferDesc, prometheus.GaugeValue, float64(value&1), controller.Name,
+		)
+	}
+
+	if value, found := featureCurrentValue(fc.getFeatureData(devicePath, featureKeepAliveTimer, 0)); found {
+		ch <- prometheus.MustNewConstMetric(
+			fc.keepAliveTimeoutDesc, prometheus.GaugeValue, float64(value)*keepAliveTimeoutUnit, controller.Name,
+		)
+	}
+
+	return nil
+}
+
+// collectTemperatureThresholds sends the over and under temperature thresholds of the composite
+// temperature and of each temperature sensor reported in the SMART log.
+func (fc *FeaturesMetricCollector) collectTemperatureThresholds(
+	ch chan<- prometheus.Metric,
+	devicePath string,
+	controller string,
+) {
+	for sensor, sensorLabel := range fc.temperatureSensors(devicePath) {
+		for threshold, thresholdSelect := range map[string]uint32{"over": 0, "under": temperatureThresholdUnderBit} {
+			featureData := fc.getFeatureData(
+				devicePath,
+				featureTemperatureThreshold,
+				sensor<<temperatureSensorShift|thresholdSelect,
+			)
+
+			value, found := featureCurrentValue(featureData)
+			if !found {
+				continue
+			}
+
+			// The thresholds are reported in Kelvin
+			ch <- prometheus.MustNewConstMetric(
+				fc.temperatureThresholdDesc,
+				prometheus.GaugeValue,
+				float64(value&0xffff)-kelvinOffset,
+				controller,
+				sensorLabel,
+				threshold,
+			)
+		}
+	}
+}
+
+// temperatureSensors returns the labels of the composite temperature and of the temperature
+// sensors reported in the SMART log of the controller, keyed by sensor. The sensors are cached
+// once the SMART log is read, until Forget is called.
+func (fc *FeaturesMetricCollector) temperatureSensors(devicePath string) map[uint32]string {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	if sensors, found := fc.sensors[devicePath]; found {
+		return sensors
+	}
+
+	sensors := map[uint32]string{0: "composite"}
+
+	smartData := fc.getSmartData(devicePath)
+	if !smartData.Exists() {
+		return sensors
+	}
+
+	for sensor := uint32(1); sensor <= temperatureSensors; sensor++ {
+		if smartData.Get("temperature_sensor_" + strconv.FormatUint(uint64(sensor), 10)).Exists() {
+			sensors[sensor] = strconv.FormatUint(uint64(sensor), 10)
+		}
+	}
+
+	fc.sensors[devicePath] = sensors
+
+	return sensors
+}
+
+// Forget clears the temperature sensors of the device, so that they are found again on the next collection.
+func (fc *FeaturesMetricCollector) Forget(devicePath string) {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	delete(fc.sensors, devicePath)
+}
@@ -0,0 +1,98 @@
+package pkg_test
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+func TestFeaturesMetricCollector(t *testing.T) {
+	t.Parallel()
+
+	subsystem := &pkg.Subsystem{Name: "nvme-subsys0"}
+	subsystem.Controllers = []*pkg.Controller{{Name: "nvme0", Subsystem: subsystem}}
+
+	var smartReads atomic.Int32
+
+	getSmartData := func(string) gjson.Result {
+		smartReads.Add(1)
+
+		// The composite temperature and a single temperature sensor
+		return gjson.Parse(`{"temperature": 311, "temperature_sensor_1": 313}`)
+	}
+
+	// The temperature thresholds in Kelvin, keyed by CDW11 (TMPSEL in bits 19:16, THSEL in bits 21:20)
+	thresholds := map[uint32]uint64{
+		0x000000: 343, // composite, over
+		0x100000: 273, // composite, under
+		0x010000: 358, // sensor 1, over
+		0x110000: 268, // sensor 1, under
+	}
+
+	getFeatureData := func(_ string, featureID int, cdw11 uint32) gjson.Result {
+		switch featureID {
+		case 0x04:
+			threshold, found := thresholds[cdw11]
+			if !found {
+				t.Errorf("unexpected temperature threshold CDW11 0x%x", cdw11)
+
+				return gjson.Result{}
+			}
+
+			return gjson.Parse(fmt.Sprintf(`{"Current": %d}`, threshold))
+		case 0x06:
+			return gjson.Parse(`{"Current": "0x1"}`)
+		case 0x07:
+			// 32 submission and 16 completion queues, 0's based
+			return gjson.Parse(`{"Current": "0x000f001f"}`)
+		case 0x08:
+			// 4 entries within 200 microseconds, 0's based threshold
+			return gjson.Parse(`{"Current": 515}`)
+		default:
+			// The host memory buffer and keep alive timer are not supported
+			return gjson.Result{}
+		}
+	}
+
+	features := pkg.NewFeaturesMetricCollector(getSmartData, getFeatureData)
+	collector := newTestCollector("features", features, subsystem)
+
+	expected := map[string]float64{
+		`nvme_feature_temperature_threshold_celsius{controller="nvme0",sensor="composite",threshold="over"}`:  70,
+		`nvme_feature_temperature_threshold_celsius{controller="nvme0",sensor="composite",threshold="under"}`: 0,
+		`nvme_feature_temperature_threshold_celsius{controller="nvme0",sensor="1",threshold="over"}`:          85,
+		`nvme_feature_temperature_threshold_celsius{controller="nvme0",sensor="1",threshold="under"}`:         -5,
+		`nvme_feature_volatile_write_cache_enabled{controller="nvme0"}`:                                       1,
+		`nvme_feature_submission_queues{controller="nvme0"}`:                                                  32,
+		`nvme_feature_completion_queues{controller="nvme0"}`:                                                  16,
+		`nvme_feature_interrupt_coalescing_threshold{controller="nvme0"}`:                                     4,
+		`nvme_feature_interrupt_coalescing_time_seconds{controller="nvme0"}`:                                  0.0002,
+	}
+
+	// The temperature sensors are read once and cached
+	for range 2 {
+		values := gatherValues(t, collector)
+
+		checkValues(t, values, expected)
+
+		if _, found := values[`nvme_feature_host_memory_buffer_enabled{controller="nvme0"}`]; found {
+			t.Error("the unsupported host memory buffer feature is exported")
+		}
+	}
+
+	if reads := smartReads.Load(); reads != 1 {
+		t.Errorf("the SMART log was read %d times, want 1", reads)
+	}
+
+	// The sensors are found again once forgotten, e.g. after a firmware update
+	features.Forget("/dev/nvme0")
+	checkValues(t, gatherValues(t, collector), expected)
+
+	if reads := smartReads.Load(); reads != 2 {
+		t.Errorf("the SMART log was read %d times after Forget, want 2", reads)
+	}
+}
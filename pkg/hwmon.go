@@ -0,0 +1,134 @@
+package pkg
+
+import (
+	"path/filepath"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg/utils"
+)
+
+// hwmonMilliDegrees is the scale of the hwmon temperature attributes.
+const hwmonMilliDegrees = 1000.0
+
+var hwmonTempInputRegex = regexp.MustCompile(`^(temp\d+)_input$`)
+
+// HwmonMetricCollector implements ControllerMetricCollector and sends the temperatures
+// exposed by the NVMe driver through the hwmon sysfs interface of each controller.
+// It does not require nvme-cli nor root privileges.
+type HwmonMetricCollector struct {
+	sysfsRoot string
+
+	temperatureDesc *prometheus.Desc
+	maxDesc         *prometheus.Desc
+	critDesc        *prometheus.Desc
+}
+
+// NewHwmonMetricCollector initializes and returns a new HwmonMetricCollector object.
+func NewHwmonMetricCollector(sysfsRoot string) *HwmonMetricCollector {
+	labels := []string{"controller", "sensor", "label"}
+
+	return &HwmonMetricCollector{
+		sysfsRoot: sysfsRoot,
+		temperatureDesc: prometheus.NewDesc(
+			"nvme_hwmon_temperature_celsius",
+			"Current temperature reported by the NVMe hwmon sensor in Celsius",
+			labels,
+			nil,
+		),
+		maxDesc: prometheus.NewDesc(
+			"nvme_hwmon_temperature_max_celsius",
+			"Maximum (over temperature threshold) temperature of the NVMe hwmon sensor in Celsius",
+			labels,
+			nil,
+		),
+		critDesc: prometheus.NewDesc(
+			"nvme_hwmon_temperature_crit_celsius",
+			"Critical temperature of the NVMe hwmon sensor in Celsius",
+			labels,
+			nil,
+		),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (hc *HwmonMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- hc.temperatureDesc
+	ch <- hc.maxDesc
+	ch <- hc.critDesc
+}
+
+// CollectControllerMetrics reads the hwmon temperature attributes of the controller
+// and sends them through the channel.
+func (hc *HwmonMetricCollector) CollectControllerMetrics(ch chan<- prometheus.Metric, controller *Controller) error {
+	hwmonDir := hc.findHwmonDir(controller.Name)
+	if hwmonDir == "" {
+		return nil
+	}
+
+	inputs, err := filepath.Glob(filepath.Join(hwmonDir, "temp*_input"))
+	if err != nil {
+		return err
+	}
+
+	for _, input := range inputs {
+		match := hwmonTempInputRegex.FindStringSubmatch(filepath.Base(input))
+		if match == nil {
+			continue
+		}
+
+		sensor := match[1]
+		label := readSysfsStringOrEmpty(filepath.Join(hwmonDir, sensor+"_label"))
+
+		hc.sendTemperature(ch, hc.temperatureDesc, input, controller.Name, sensor, label)
+		hc.sendTemperature(ch, hc.maxDesc, filepath.Join(hwmonDir, sensor+"_max"), controller.Name, sensor, label)
+		hc.sendTemperature(ch, hc.critDesc, filepath.Join(hwmonDir, sensor+"_crit"), controller.Name, sensor, label)
+	}
+
+	return nil
+}
+
+// findHwmonDir returns the hwmon directory of the controller.
+// Newer kernels register the hwmon device under the NVMe controller,
+// older ones (5.5 to 5.9) under the PCI device.
+func (hc *HwmonMetricCollector) findHwmonDir(controller string) string {
+	if controller == "" {
+		return ""
+	}
+
+	controllerDir := filepath.Join(hc.sysfsRoot, "class", "nvme", controller)
+
+	for _, pattern := range []string{
+		filepath.Join(controllerDir, "hwmon*"),
+		filepath.Join(controllerDir, "device", "hwmon", "hwmon*"),
+	} {
+		matches, err := filepath.Glob(pattern)
+		if err == nil && len(matches) > 0 {
+			return matches[0]
+		}
+	}
+
+	return ""
+}
+
+// sendTemperature reads a millidegree Celsius attribute and sends it through the channel.
+// Missing attributes (e.g. sensors without a crit threshold) are skipped.
+func (hc *HwmonMetricCollector) sendTemperature(
+	ch chan<- prometheus.Metric,
+	desc *prometheus.Desc,
+	path string,
+	labels ...string,
+) {
+	value, err := utils.ReadSysfsInt(path)
+	if err != nil {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		desc,
+		prometheus.GaugeValue,
+		float64(value)/hwmonMilliDegrees,
+		labels...,
+	)
+}
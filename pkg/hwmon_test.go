@@ -0,0 +1,62 @@
+package pkg_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+func TestHwmonMetricCollector(t *testing.T) {
+	t.Parallel()
+
+	sysfsRoot := t.TempDir()
+	classDir := filepath.Join(sysfsRoot, "class", "nvme")
+
+	// nvme0 registers its hwmon device under the controller, nvme1 under the PCI device (kernels 5.5 to 5.9)
+	writeSysfsAttributes(t, filepath.Join(classDir, "nvme0", "hwmon1"), map[string]string{
+		"temp1_input": "38850",
+		"temp1_label": "Composite",
+		"temp1_max":   "84850",
+		"temp1_crit":  "87850",
+		"temp2_input": "41850",
+		"temp2_label": "Sensor 1",
+	})
+	writeSysfsAttributes(t, filepath.Join(classDir, "nvme1", "device", "hwmon", "hwmon2"), map[string]string{
+		"temp1_input": "35850",
+		"temp1_label": "Composite",
+	})
+
+	// Both controllers reach the same shared namespace, attached to the first one only
+	subsystem := &pkg.Subsystem{Name: "nvme-subsys0"}
+	nvme0 := &pkg.Controller{Name: "nvme0", Subsystem: subsystem}
+	nvme1 := &pkg.Controller{Name: "nvme1", Subsystem: subsystem}
+	nvme0.Namespaces = []*pkg.Namespace{{Name: "nvme0n1", DevicePath: "/dev/nvme0n1", NSID: 1, Controller: nvme0}}
+	subsystem.Controllers = []*pkg.Controller{nvme0, nvme1}
+
+	collector := pkg.NewCompositeCollector(
+		[]pkg.NamedMetricCollector{
+			pkg.NewNamedMetricCollector("hwmon", pkg.NewHwmonMetricCollector(sysfsRoot)),
+		},
+		func() []*pkg.Subsystem {
+			return []*pkg.Subsystem{subsystem}
+		},
+	)
+
+	values := gatherValues(t, collector)
+
+	checkValues(t, values, map[string]float64{
+		`nvme_hwmon_temperature_celsius{controller="nvme0",label="Composite",sensor="temp1"}`:      38.85,
+		`nvme_hwmon_temperature_max_celsius{controller="nvme0",label="Composite",sensor="temp1"}`:  84.85,
+		`nvme_hwmon_temperature_crit_celsius{controller="nvme0",label="Composite",sensor="temp1"}`: 87.85,
+		`nvme_hwmon_temperature_celsius{controller="nvme0",label="Sensor 1",sensor="temp2"}`:       41.85,
+		// The controller without namespaces has its temperatures too
+		`nvme_hwmon_temperature_celsius{controller="nvme1",label="Composite",sensor="temp1"}`: 35.85,
+	})
+
+	// Missing thresholds are skipped
+	missing := `nvme_hwmon_temperature_max_celsius{controller="nvme0",label="Sensor 1",sensor="temp2"}`
+	if _, found := values[missing]; found {
+		t.Error("temp2_max of nvme0 is missing but exported")
+	}
+}
@@ -0,0 +1,65 @@
+package ioctl
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// nvmeIoctlAdminCmd is NVME_IOCTL_ADMIN_CMD, _IOWR('N', 0x41, struct nvme_admin_cmd).
+const nvmeIoctlAdminCmd = 0xC0484E41
+
+// passthruCommand mirrors struct nvme_passthru_cmd from linux/nvme_ioctl.h.
+type passthruCommand struct {
+	opcode      uint8
+	flags       uint8
+	rsvd1       uint16
+	nsid        uint32
+	cdw2        uint32
+	cdw3        uint32
+	metadata    uint64
+	addr        uint64
+	metadataLen uint32
+	dataLen     uint32
+	cdw10       uint32
+	cdw11       uint32
+	cdw12       uint32
+	cdw13       uint32
+	cdw14       uint32
+	cdw15       uint32
+	timeoutMs   uint32
+	result      uint32
+}
+
+// submitAdminCommand opens the device and submits the admin command,
+// filling data with the bytes transferred by the controller.
+func submitAdminCommand(devicePath string, cmd *passthruCommand, data []byte) error {
+	file, err := os.OpenFile(devicePath, os.O_RDONLY, 0)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", devicePath, err)
+	}
+	defer file.Close()
+
+	cmd.addr = uint64(uintptr(unsafe.Pointer(&data[0])))
+	cmd.dataLen = uint32(len(data))
+
+	status, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL,
+		file.Fd(),
+		nvmeIoctlAdminCmd,
+		uintptr(unsafe.Pointer(cmd)),
+	)
+	runtime.KeepAlive(data)
+
+	if errno != 0 {
+		return fmt.Errorf("error submitting admin command 0x%02x to %s: %w", cmd.opcode, devicePath, errno)
+	}
+
+	if status != 0 {
+		return fmt.Errorf("admin command 0x%02x on %s failed with NVMe status 0x%x", cmd.opcode, devicePath, status)
+	}
+
+	return nil
+}
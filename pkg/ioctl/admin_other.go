@@ -0,0 +1,18 @@
+//go:build !linux
+
+package ioctl
+
+import "errors"
+
+// passthruCommand is only supported on Linux.
+type passthruCommand struct {
+	opcode uint8
+	nsid   uint32
+	cdw10  uint32
+	cdw11  uint32
+}
+
+// submitAdminCommand always fails, since NVMe admin passthrough is only supported on Linux.
+func submitAdminCommand(string, *passthruCommand, []byte) error {
+	return errors.New("NVMe admin passthrough is only supported on Linux")
+}
@@ -0,0 +1,70 @@
+// Package ioctl issues NVMe admin commands through the NVME_IOCTL_ADMIN_CMD
+// passthrough interface and converts the log pages decoded by the logpage
+// package into the same JSON format produced by nvme-cli, so that the data
+// can be consumed by the existing metric providers without forking nvme-cli.
+package ioctl
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Admin command opcodes.
+const (
+	opcodeGetLogPage = 0x02
+	opcodeIdentify   = 0x06
+)
+
+// cnsNamespace is the Identify CNS value of the Identify Namespace data structure.
+const cnsNamespace = 0x00
+
+// nsidAll addresses the controller (or all namespaces) in log page requests.
+const nsidAll = 0xFFFFFFFF
+
+// identifyDataSize is the size of every Identify data structure.
+const identifyDataSize = 4096
+
+var errInvalidLength = errors.New("log page length must be a positive multiple of 4 bytes")
+
+// GetLogPage issues a Get Log Page admin command and returns length bytes of the log page.
+func GetLogPage(devicePath string, logID uint8, nsid uint32, length int) ([]byte, error) {
+	if length <= 0 || length%4 != 0 {
+		return nil, errInvalidLength
+	}
+
+	// NUMD is a 0's based number of dwords, split in a lower (CDW10) and upper (CDW11) word
+	numd := uint32(length/4 - 1)
+	data := make([]byte, length)
+
+	cmd := passthruCommand{
+		opcode: opcodeGetLogPage,
+		nsid:   nsid,
+		cdw10:  uint32(logID) | (numd&0xFFFF)<<16,
+		cdw11:  numd >> 16,
+	}
+
+	err := submitAdminCommand(devicePath, &cmd, data)
+	if err != nil {
+		return nil, fmt.Errorf("error reading log page 0x%02x: %w", logID, err)
+	}
+
+	return data, nil
+}
+
+// Identify issues an Identify admin command and returns the 4096 bytes data structure.
+func Identify(devicePath string, cns uint8, nsid uint32) ([]byte, error) {
+	data := make([]byte, identifyDataSize)
+
+	cmd := passthruCommand{
+		opcode: opcodeIdentify,
+		nsid:   nsid,
+		cdw10:  uint32(cns),
+	}
+
+	err := submitAdminCommand(devicePath, &cmd, data)
+	if err != nil {
+		return nil, fmt.Errorf("error identifying CNS 0x%02x: %w", cns, err)
+	}
+
+	return data, nil
+}
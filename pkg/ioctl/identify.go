@@ -0,0 +1,37 @@
+package ioctl
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Identify Namespace data structure layout.
+const (
+	identifyNamespaceNUSE   = 16
+	identifyNamespaceFLBAS  = 26
+	identifyNamespaceLBAF   = 128
+	identifyLBAFormatSize   = 4
+	identifyLBAFormatLBADS  = 2
+	identifyFLBASLowerMask  = 0x0F
+	identifyFLBASUpperMask  = 0x60
+	identifyFLBASUpperShift = 1
+)
+
+// DecodeNamespaceUsedBytes decodes the Identify Namespace data structure and
+// returns the namespace utilization (NUSE) in bytes, computed with the size
+// of the LBA format in use.
+func DecodeNamespaceUsedBytes(data []byte) (int64, error) {
+	if len(data) < identifyDataSize {
+		return 0, fmt.Errorf("identify namespace data is %d bytes long, expected %d", len(data), identifyDataSize)
+	}
+
+	// The LBA format index is split between FLBAS bits 3:0 (lower) and bits 6:5 (upper)
+	flbas := data[identifyNamespaceFLBAS]
+	format := int(flbas&identifyFLBASLowerMask) | int(flbas&identifyFLBASUpperMask)>>identifyFLBASUpperShift
+
+	lbads := data[identifyNamespaceLBAF+format*identifyLBAFormatSize+identifyLBAFormatLBADS]
+
+	nuse := binary.LittleEndian.Uint64(data[identifyNamespaceNUSE:])
+
+	return int64(nuse << lbads), nil
+}
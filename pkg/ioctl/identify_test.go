@@ -0,0 +1,65 @@
+package ioctl_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg/ioctl"
+)
+
+// Identify Namespace data structure offsets.
+const (
+	identifySize = 4096
+	nuseOffset   = 16
+	flbasOffset  = 26
+	// lbadsOffset is the offset of LBADS in the first LBA format, each LBA format is 4 bytes long
+	lbadsOffset = 130
+)
+
+func TestDecodeNamespaceUsedBytes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		flbas byte
+		// format is the LBA format index selected by flbas
+		format int
+		lbads  byte
+		nuse   uint64
+		want   int64
+	}{
+		{name: "512 bytes sectors", flbas: 0x00, format: 0, lbads: 9, nuse: 1000, want: 512000},
+		{name: "4096 bytes sectors", flbas: 0x01, format: 1, lbads: 12, nuse: 1000, want: 4096000},
+		{name: "metadata at end of LBA", flbas: 0x12, format: 2, lbads: 12, nuse: 3, want: 12288},
+		{name: "upper format bits", flbas: 0x21, format: 17, lbads: 12, nuse: 2, want: 8192},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := make([]byte, identifySize)
+			binary.LittleEndian.PutUint64(data[nuseOffset:], test.nuse)
+			data[flbasOffset] = test.flbas
+			data[lbadsOffset+test.format*4] = test.lbads
+
+			got, err := ioctl.DecodeNamespaceUsedBytes(data)
+			if err != nil {
+				t.Fatalf("DecodeNamespaceUsedBytes() error = %v", err)
+			}
+
+			if got != test.want {
+				t.Errorf("DecodeNamespaceUsedBytes() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDecodeNamespaceUsedBytesShortData(t *testing.T) {
+	t.Parallel()
+
+	_, err := ioctl.DecodeNamespaceUsedBytes(make([]byte, identifySize-1))
+	if err == nil {
+		t.Error("DecodeNamespaceUsedBytes() of a short data structure returned no error")
+	}
+}
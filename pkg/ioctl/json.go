@@ -0,0 +1,96 @@
+package ioctl
+
+import (
+	"strconv"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg/logpage"
+)
+
+// smartLogJSON maps the SMART log fields to the JSON keys of nvme smart-log -o json.
+func smartLogJSON(smartLog *logpage.SmartLog) map[string]interface{} {
+	smartJSON := map[string]interface{}{
+		"critical_warning":                       smartLog.CriticalWarning,
+		"temperature":                            smartLog.CompositeTemperature,
+		"avail_spare":                            smartLog.AvailableSpare,
+		"spare_thresh":                           smartLog.AvailableSpareThreshold,
+		"percent_used":                           smartLog.PercentageUsed,
+		"endurance_grp_critical_warning_summary": smartLog.EnduranceGroupCriticalWarningSummary,
+		"data_units_read":                        smartLog.DataUnitsRead.Float64(),
+		"data_units_written":                     smartLog.DataUnitsWritten.Float64(),
+		"host_read_commands":                     smartLog.HostReadCommands.Float64(),
+		"host_write_commands":                    smartLog.HostWriteCommands.Float64(),
+		"controller_busy_time":                   smartLog.ControllerBusyTime.Float64(),
+		"power_cycles":                           smartLog.PowerCycles.Float64(),
+		"power_on_hours":                         smartLog.PowerOnHours.Float64(),
+		"unsafe_shutdowns":                       smartLog.UnsafeShutdowns.Float64(),
+		"media_errors":                           smartLog.MediaErrors.Float64(),
+		"num_err_log_entries":                    smartLog.ErrorLogEntries.Float64(),
+		"warning_temp_time":                      smartLog.WarningCompositeTemperatureTime,
+		"critical_comp_time":                     smartLog.CriticalCompositeTemperatureTime,
+		"thm_temp1_trans_count":                  smartLog.ThermalManagementTemp1TransitionCount,
+		"thm_temp2_trans_count":                  smartLog.ThermalManagementTemp2TransitionCount,
+		"thm_temp1_total_time":                   smartLog.ThermalManagementTemp1TotalTime,
+		"thm_temp2_total_time":                   smartLog.ThermalManagementTemp2TotalTime,
+	}
+
+	// Unimplemented temperature sensors report 0 and are omitted like nvme-cli does
+	for i, temperature := range smartLog.TemperatureSensors {
+		if temperature != 0 {
+			smartJSON["temperature_sensor_"+strconv.Itoa(i+1)] = temperature
+		}
+	}
+
+	return smartJSON
+}
+
+// ocpSmartLogJSON maps the OCP SMART extended log fields to the JSON keys
+// of nvme ocp smart-add-log -o json. The Capacitor Health field is mapped
+// to both its names, since nvme-cli renamed it PLP Health for OCP 2.5.
+func ocpSmartLogJSON(ocpLog *logpage.OcpSmartLog) map[string]interface{} {
+	return map[string]interface{}{
+		"Physical media units written": map[string]interface{}{
+			"hi": ocpLog.PhysicalMediaUnitsWritten.Hi,
+			"lo": ocpLog.PhysicalMediaUnitsWritten.Lo,
+		},
+		"Physical media units read": map[string]interface{}{
+			"hi": ocpLog.PhysicalMediaUnitsRead.Hi,
+			"lo": ocpLog.PhysicalMediaUnitsRead.Lo,
+		},
+		"Bad user nand blocks - Raw":          ocpLog.BadUserNANDBlocksRaw,
+		"Bad user nand blocks - Normalized":   ocpLog.BadUserNANDBlocksNormalized,
+		"Bad system nand blocks - Raw":        ocpLog.BadSystemNANDBlocksRaw,
+		"Bad system nand blocks - Normalized": ocpLog.BadSystemNANDBlocksNormalized,
+		"XOR recovery count":                  ocpLog.XORRecoveryCount,
+		"Uncorrectable read error count":      ocpLog.UncorrectableReadErrorCount,
+		"Soft ecc error count":                ocpLog.SoftECCErrorCount,
+		"End to end detected errors":          ocpLog.EndToEndDetectedErrors,
+		"End to end corrected errors":         ocpLog.EndToEndCorrectedErrors,
+		"System data percent used":            ocpLog.SystemDataPercentUsed,
+		"Refresh counts":                      ocpLog.RefreshCounts,
+		"Max User data erase counts":          ocpLog.MaxUserDataEraseCount,
+		"Min User data erase counts":          ocpLog.MinUserDataEraseCount,
+		"Number of Thermal throttling events": ocpLog.ThermalThrottlingEvents,
+		"Current throttling status":           ocpLog.ThermalThrottlingStatus,
+		"Errata Version Field":                ocpLog.DSSDErrataVersion,
+		"Point Version Field":                 ocpLog.DSSDPointVersion,
+		"Minor Version Field":                 ocpLog.DSSDMinorVersion,
+		"Major Version Field":                 ocpLog.DSSDMajorVersion,
+		"PCIe correctable error count":        ocpLog.PCIeCorrectableErrorCount,
+		"Incomplete shutdowns":                ocpLog.IncompleteShutdowns,
+		"Percent free blocks":                 ocpLog.PercentFreeBlocks,
+		"Capacitor health":                    ocpLog.CapacitorHealth,
+		"PLP Health":                          ocpLog.CapacitorHealth,
+		"NVMe Errata Version":                 ocpLog.NVMeBaseErrataVersion,
+		"NVMe Command Set Errata Version":     ocpLog.NVMeCommandSetErrataVersion,
+		"Unaligned I/O":                       ocpLog.UnalignedIO,
+		"Security Version Number":             ocpLog.SecurityVersionNumber,
+		"NUSE - Namespace utilization":        ocpLog.TotalNUSE,
+		"PLP start count":                     ocpLog.PLPStartCount.Float64(),
+		"Endurance estimate":                  ocpLog.EnduranceEstimate.Float64(),
+		"PCIe Link Retraining Count":          ocpLog.PCIeLinkRetrainingCount,
+		"Power State Change Count":            ocpLog.PowerStateChangeCount,
+		"Lowest Permitted Firmware Revision":  ocpLog.LowestPermittedFirmwareRevision,
+		"Log page version":                    ocpLog.LogPageVersion,
+		"Log page GUID":                       ocpLog.LogPageGUID,
+	}
+}
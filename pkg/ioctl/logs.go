@@ -0,0 +1,64 @@
+package ioctl
+
+import (
+	"github.com/tidwall/gjson"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg/logpage"
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg/utils"
+)
+
+// readLog reads a log page, decodes it and returns it as a gjson.Result
+// with the nvme-cli JSON keys.
+func readLog[T any](
+	devicePath string,
+	logID uint8,
+	length int,
+	decode func([]byte) (T, error),
+	toJSON func(T) map[string]interface{},
+) (gjson.Result, error) {
+	data, err := GetLogPage(devicePath, logID, nsidAll, length)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+
+	return decodeLog(data, decode, toJSON)
+}
+
+// decodeLog decodes the raw bytes of a log page and returns it
+// as a gjson.Result with the nvme-cli JSON keys.
+func decodeLog[T any](
+	data []byte,
+	decode func([]byte) (T, error),
+	toJSON func(T) map[string]interface{},
+) (gjson.Result, error) {
+	decoded, err := decode(data)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+
+	return gjson.Parse(utils.MapToJSONString(toJSON(decoded))), nil
+}
+
+// SmartLog reads the SMART / Health Information log page of the device
+// in the same JSON format of nvme smart-log.
+func SmartLog(devicePath string) (gjson.Result, error) {
+	return readLog(devicePath, logpage.LogSmartHealth, logpage.SmartLogSize,
+		logpage.DecodeSmartLog, smartLogJSON)
+}
+
+// OcpSmartLog reads the OCP SMART extended log page of the device
+// in the same JSON format of nvme ocp smart-add-log.
+func OcpSmartLog(devicePath string) (gjson.Result, error) {
+	return readLog(devicePath, logpage.LogOcpSmartExtended, logpage.OcpSmartLogSize,
+		logpage.DecodeOcpSmartLog, ocpSmartLogJSON)
+}
+
+// NamespaceUsedBytes returns the number of bytes allocated to the namespace.
+func NamespaceUsedBytes(devicePath string, nsid int64) (int64, error) {
+	namespace, err := Identify(devicePath, cnsNamespace, uint32(nsid))
+	if err != nil {
+		return 0, err
+	}
+
+	return DecodeNamespaceUsedBytes(namespace)
+}
@@ -0,0 +1,124 @@
+package ioctl
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg/logpage"
+)
+
+// smartLogFixture returns a SMART / Health Information log page with the fields at the spec offsets.
+func smartLogFixture() []byte {
+	data := make([]byte, logpage.SmartLogSize)
+
+	data[0] = 0x04
+	binary.LittleEndian.PutUint16(data[1:], 310)
+	data[3] = 100
+	data[4] = 10
+	data[5] = 3
+	binary.LittleEndian.PutUint64(data[32:], 123456)
+	// Data units written above 2^64
+	binary.LittleEndian.PutUint64(data[48:], 1)
+	binary.LittleEndian.PutUint64(data[56:], 1)
+	binary.LittleEndian.PutUint64(data[128:], 8760)
+	binary.LittleEndian.PutUint64(data[176:], 42)
+	binary.LittleEndian.PutUint32(data[192:], 7)
+	binary.LittleEndian.PutUint16(data[200:], 305)
+	binary.LittleEndian.PutUint16(data[204:], 315)
+	binary.LittleEndian.PutUint32(data[224:], 60)
+
+	return data
+}
+
+// ocpSmartLogFixture returns an OCP SMART extended log page with the fields at the spec offsets.
+func ocpSmartLogFixture() []byte {
+	data := make([]byte, logpage.OcpSmartLogSize)
+
+	binary.LittleEndian.PutUint64(data[0:], 4096)
+	binary.LittleEndian.PutUint64(data[8:], 2)
+	binary.LittleEndian.PutUint16(data[38:], 100)
+	binary.LittleEndian.PutUint64(data[48:], 5)
+	binary.LittleEndian.PutUint16(data[128:], 95)
+	copy(data[208:], "FW01    ")
+	binary.LittleEndian.PutUint16(data[494:], 4)
+	binary.LittleEndian.PutUint64(data[496:], 0xa4f2bfea2810afc5)
+	binary.LittleEndian.PutUint64(data[504:], 0xafd514c97c6f4f9c)
+
+	return data
+}
+
+func TestDecodeLogSmart(t *testing.T) {
+	t.Parallel()
+
+	smartLog, err := decodeLog(smartLogFixture(), logpage.DecodeSmartLog, smartLogJSON)
+	if err != nil {
+		t.Fatalf("decodeLog() error = %v", err)
+	}
+
+	tests := map[string]float64{
+		"critical_warning":     4,
+		"temperature":          310,
+		"avail_spare":          100,
+		"spare_thresh":         10,
+		"percent_used":         3,
+		"data_units_read":      123456,
+		"data_units_written":   1<<64 + 1,
+		"power_on_hours":       8760,
+		"num_err_log_entries":  42,
+		"warning_temp_time":    7,
+		"temperature_sensor_1": 305,
+		"temperature_sensor_3": 315,
+		"thm_temp1_total_time": 60,
+	}
+
+	for key, want := range tests {
+		if got := smartLog.Get(key); !got.Exists() || got.Float() != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+
+	// Unimplemented sensors are omitted like nvme-cli does
+	if smartLog.Get("temperature_sensor_2").Exists() {
+		t.Error("temperature_sensor_2 is not implemented but exists")
+	}
+}
+
+func TestDecodeLogOcpSmart(t *testing.T) {
+	t.Parallel()
+
+	ocpLog, err := decodeLog(ocpSmartLogFixture(), logpage.DecodeOcpSmartLog, ocpSmartLogJSON)
+	if err != nil {
+		t.Fatalf("decodeLog() error = %v", err)
+	}
+
+	tests := map[string]string{
+		"Physical media units written.lo":    "4096",
+		"Physical media units written.hi":    "2",
+		"Bad user nand blocks - Normalized":  "100",
+		"XOR recovery count":                 "5",
+		"Capacitor health":                   "95",
+		"PLP Health":                         "95",
+		"Lowest Permitted Firmware Revision": "FW01",
+		"Log page version":                   "4",
+		"Log page GUID":                      logpage.OcpSmartLogGUID,
+	}
+
+	for key, want := range tests {
+		if got := ocpLog.Get(key); got.String() != want {
+			t.Errorf("%s = %q, want %q", key, got.String(), want)
+		}
+	}
+}
+
+func TestDecodeLogOcpSmartGUIDMismatch(t *testing.T) {
+	t.Parallel()
+
+	data := ocpSmartLogFixture()
+	data[496] = 0
+
+	_, err := decodeLog(data, logpage.DecodeOcpSmartLog, ocpSmartLogJSON)
+	if !errors.Is(err, logpage.ErrGUIDMismatch) {
+		t.Errorf("decodeLog() error = %v, want %v", err, logpage.ErrGUIDMismatch)
+	}
+}
@@ -0,0 +1,62 @@
+package logpage
+
+// ErrorLogEntrySize is the size of each Error Information log entry.
+const ErrorLogEntrySize = 64
+
+// ErrorLogEntry is an entry of the Error Information log page (01h).
+type ErrorLogEntry struct {
+	// ErrorCount is the unique identifier of the error, 0 means the entry is unused
+	ErrorCount uint64
+	// SubmissionQueueID is the queue of the command that failed (0 for the admin queue)
+	SubmissionQueueID uint16
+	CommandID         uint16
+	// StatusField is the status of the completion queue entry, without the phase tag
+	StatusField uint16
+	PhaseTag    uint8
+	// ParameterErrorLocation is the byte (bits 7:0) and bit (bits 10:8)
+	// of the command parameter that caused the error
+	ParameterErrorLocation uint16
+	LBA                    uint64
+	NamespaceID            uint32
+	// VendorSpecificLogPage is the log page with additional information, 0 if not available
+	VendorSpecificLogPage uint8
+	TransportType         uint8
+	CommandSpecific       uint64
+	TransportTypeSpecific uint16
+}
+
+// DecodeErrorLog decodes the Error Information log page (01h).
+// The page is made of as many entries as the controller supports (ELPE + 1),
+// unused entries (error count 0) are skipped.
+func DecodeErrorLog(data []byte) ([]ErrorLogEntry, error) {
+	err := checkSize("Error Information log", data, ErrorLogEntrySize)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []ErrorLogEntry{}
+
+	for offset := 0; offset+ErrorLogEntrySize <= len(data); offset += ErrorLogEntrySize {
+		entry := data[offset : offset+ErrorLogEntrySize]
+		if le64(entry, 0) == 0 {
+			continue
+		}
+
+		entries = append(entries, ErrorLogEntry{
+			ErrorCount:             le64(entry, 0),
+			SubmissionQueueID:      le16(entry, 8),
+			CommandID:              le16(entry, 10),
+			StatusField:            le16(entry, 12) >> 1,
+			PhaseTag:               uint8(le16(entry, 12) & 0x1),
+			ParameterErrorLocation: le16(entry, 14),
+			LBA:                    le64(entry, 16),
+			NamespaceID:            le32(entry, 24),
+			VendorSpecificLogPage:  entry[28],
+			TransportType:          entry[29],
+			CommandSpecific:        le64(entry, 32),
+			TransportTypeSpecific:  le16(entry, 40),
+		})
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,41 @@
+package logpage
+
+// FirmwareSlotLogSize is the size of the Firmware Slot Information log page.
+const FirmwareSlotLogSize = 512
+
+// FirmwareSlots is the maximum number of firmware slots.
+const FirmwareSlots = 7
+
+const (
+	firmwareRevisionOffset = 8
+	firmwareRevisionSize   = 8
+)
+
+// FirmwareSlotLog is the Firmware Slot Information log page (03h).
+type FirmwareSlotLog struct {
+	// ActiveSlot is the slot of the running firmware (AFI bits 2:0)
+	ActiveSlot uint8
+	// NextResetSlot is the slot activated at the next reset (AFI bits 6:4), 0 if not set
+	NextResetSlot uint8
+	// Revisions are the firmware revisions of slots 1 to 7, empty if the slot is unused
+	Revisions [FirmwareSlots]string
+}
+
+// DecodeFirmwareSlotLog decodes the Firmware Slot Information log page (03h).
+func DecodeFirmwareSlotLog(data []byte) (*FirmwareSlotLog, error) {
+	err := checkSize("Firmware Slot log", data, FirmwareSlotLogSize)
+	if err != nil {
+		return nil, err
+	}
+
+	firmwareLog := &FirmwareSlotLog{
+		ActiveSlot:    data[0] & 0x07,
+		NextResetSlot: (data[0] >> 4) & 0x07,
+	}
+
+	for slot := range firmwareLog.Revisions {
+		firmwareLog.Revisions[slot] = ascii(data, firmwareRevisionOffset+slot*firmwareRevisionSize, firmwareRevisionSize)
+	}
+
+	return firmwareLog, nil
+}
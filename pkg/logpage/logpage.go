@@ -0,0 +1,82 @@
+// Package logpage decodes the raw bytes of the NVMe log pages into Go structs,
+// independently of how the log pages are fetched (admin passthrough, nvme-cli
+// binary output, or files).
+//
+// Field semantics follow the NVM Express Base Specification Revision 2.1 and
+// the OCP Datacenter NVMe SSD Specification v2.5.
+package logpage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Log page identifiers.
+const (
+	LogErrorInformation = 0x01
+	LogSmartHealth      = 0x02
+	LogFirmwareSlot     = 0x03
+	LogOcpSmartExtended = 0xC0
+)
+
+// ErrShortPage is returned when the page is shorter than the size defined by the specification.
+var ErrShortPage = errors.New("log page too short")
+
+// Uint128 is a 128 bits unsigned integer, used by the specification for lifetime counters.
+type Uint128 struct {
+	Lo uint64
+	Hi uint64
+}
+
+// Float64 returns the counter as a float64, which may lose precision
+// above 2^53 but is the type of every Prometheus sample.
+func (u Uint128) Float64() float64 {
+	return float64(u.Hi)*math.Pow(2, 64) + float64(u.Lo)
+}
+
+// checkSize returns ErrShortPage if the page is shorter than the expected size.
+func checkSize(name string, data []byte, size int) error {
+	if len(data) < size {
+		return fmt.Errorf("%w: %s page is %d bytes long, expected at least %d", ErrShortPage, name, len(data), size)
+	}
+
+	return nil
+}
+
+func le16(data []byte, offset int) uint16 {
+	return binary.LittleEndian.Uint16(data[offset:])
+}
+
+func le32(data []byte, offset int) uint32 {
+	return binary.LittleEndian.Uint32(data[offset:])
+}
+
+func le64(data []byte, offset int) uint64 {
+	return binary.LittleEndian.Uint64(data[offset:])
+}
+
+func le128(data []byte, offset int) Uint128 {
+	return Uint128{
+		Lo: le64(data, offset),
+		Hi: le64(data, offset+8),
+	}
+}
+
+// leN decodes a little endian integer of up to 8 bytes (e.g. the 6 bytes raw NAND block counters).
+func leN(data []byte, offset int, size int) uint64 {
+	var value uint64
+
+	for i := size - 1; i >= 0; i-- {
+		value = value<<8 | uint64(data[offset+i])
+	}
+
+	return value
+}
+
+// ascii decodes a space padded ASCII string field.
+func ascii(data []byte, offset int, size int) string {
+	return strings.TrimRight(string(data[offset:offset+size]), " \x00")
+}
@@ -0,0 +1,366 @@
+package logpage_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg/logpage"
+)
+
+// putUint128 writes a 128 bits little endian counter.
+func putUint128(data []byte, offset int, lo uint64, hi uint64) {
+	binary.LittleEndian.PutUint64(data[offset:], lo)
+	binary.LittleEndian.PutUint64(data[offset+8:], hi)
+}
+
+func TestUint128Float64(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value logpage.Uint128
+		want  float64
+	}{
+		{value: logpage.Uint128{}, want: 0},
+		{value: logpage.Uint128{Lo: 42}, want: 42},
+		{value: logpage.Uint128{Lo: math.MaxUint64}, want: math.MaxUint64},
+		{value: logpage.Uint128{Hi: 1}, want: math.Pow(2, 64)},
+		{value: logpage.Uint128{Lo: 1 << 20, Hi: 3}, want: 3*math.Pow(2, 64) + 1<<20},
+	}
+
+	for _, test := range tests {
+		if got := test.value.Float64(); got != test.want {
+			t.Errorf("%+v.Float64() = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestDecodeSmartLog(t *testing.T) {
+	t.Parallel()
+
+	data := make([]byte, logpage.SmartLogSize)
+	data[0] = 0x05
+	binary.LittleEndian.PutUint16(data[1:], 318)
+	data[3] = 97
+	data[4] = 10
+	data[5] = 12
+	data[6] = 0x01
+	putUint128(data, 32, 0x1111, 0)
+	putUint128(data, 48, 0x2222, 0x1)
+	putUint128(data, 64, 0x3333, 0)
+	putUint128(data, 80, 0x4444, 0)
+	putUint128(data, 96, 0x5555, 0)
+	putUint128(data, 112, 0x6666, 0)
+	putUint128(data, 128, 0x7777, 0)
+	putUint128(data, 144, 0x8888, 0)
+	putUint128(data, 160, 0x9999, 0)
+	putUint128(data, 176, 0xaaaa, 0xbbbb)
+	binary.LittleEndian.PutUint32(data[192:], 11)
+	binary.LittleEndian.PutUint32(data[196:], 12)
+
+	for sensor := range logpage.SmartTemperatureSensors {
+		binary.LittleEndian.PutUint16(data[200+2*sensor:], uint16(300+sensor))
+	}
+
+	binary.LittleEndian.PutUint32(data[216:], 21)
+	binary.LittleEndian.PutUint32(data[220:], 22)
+	binary.LittleEndian.PutUint32(data[224:], 23)
+	binary.LittleEndian.PutUint32(data[228:], 24)
+
+	got, err := logpage.DecodeSmartLog(data)
+	if err != nil {
+		t.Fatalf("DecodeSmartLog() error = %v", err)
+	}
+
+	want := &logpage.SmartLog{
+		CriticalWarning:                      0x05,
+		CompositeTemperature:                 318,
+		AvailableSpare:                       97,
+		AvailableSpareThreshold:              10,
+		PercentageUsed:                       12,
+		EnduranceGroupCriticalWarningSummary: 0x01,
+		DataUnitsRead:                        logpage.Uint128{Lo: 0x1111},
+		DataUnitsWritten:                     logpage.Uint128{Lo: 0x2222, Hi: 0x1},
+		HostReadCommands:                     logpage.Uint128{Lo: 0x3333},
+		HostWriteCommands:                    logpage.Uint128{Lo: 0x4444},
+		ControllerBusyTime:                   logpage.Uint128{Lo: 0x5555},
+		PowerCycles:                          logpage.Uint128{Lo: 0x6666},
+		PowerOnHours:                         logpage.Uint128{Lo: 0x7777},
+		UnsafeShutdowns:                      logpage.Uint128{Lo: 0x8888},
+		MediaErrors:                          logpage.Uint128{Lo: 0x9999},
+		ErrorLogEntries:                      logpage.Uint128{Lo: 0xaaaa, Hi: 0xbbbb},
+		WarningCompositeTemperatureTime:      11,
+		CriticalCompositeTemperatureTime:     12,
+		TemperatureSensors: [logpage.SmartTemperatureSensors]uint16{
+			300, 301, 302, 303, 304, 305, 306, 307,
+		},
+		ThermalManagementTemp1TransitionCount: 21,
+		ThermalManagementTemp2TransitionCount: 22,
+		ThermalManagementTemp1TotalTime:       23,
+		ThermalManagementTemp2TotalTime:       24,
+	}
+
+	if *got != *want {
+		t.Errorf("DecodeSmartLog() = %+v, want %+v", *got, *want)
+	}
+}
+
+func TestDecodeErrorLog(t *testing.T) {
+	t.Parallel()
+
+	// Three entries, the second one is unused
+	data := make([]byte, 3*logpage.ErrorLogEntrySize)
+
+	entry := data[2*logpage.ErrorLogEntrySize:]
+	binary.LittleEndian.PutUint64(entry[0:], 7)
+	binary.LittleEndian.PutUint16(entry[8:], 1)
+	binary.LittleEndian.PutUint16(entry[10:], 0x1234)
+	// Status Code Type 0h, Status Code 02h (Invalid Field in Command), phase tag set
+	binary.LittleEndian.PutUint16(entry[12:], 0x02<<1|1)
+	binary.LittleEndian.PutUint16(entry[14:], 0x0228)
+	binary.LittleEndian.PutUint64(entry[16:], 0xdeadbeef)
+	binary.LittleEndian.PutUint32(entry[24:], 1)
+	entry[28] = 0xc0
+	entry[29] = 0x03
+	binary.LittleEndian.PutUint64(entry[32:], 0x55)
+	binary.LittleEndian.PutUint16(entry[40:], 0x66)
+
+	binary.LittleEndian.PutUint64(data[0:], 8)
+
+	got, err := logpage.DecodeErrorLog(data)
+	if err != nil {
+		t.Fatalf("DecodeErrorLog() error = %v", err)
+	}
+
+	want := []logpage.ErrorLogEntry{
+		{ErrorCount: 8},
+		{
+			ErrorCount:             7,
+			SubmissionQueueID:      1,
+			CommandID:              0x1234,
+			StatusField:            0x02,
+			PhaseTag:               1,
+			ParameterErrorLocation: 0x0228,
+			LBA:                    0xdeadbeef,
+			NamespaceID:            1,
+			VendorSpecificLogPage:  0xc0,
+			TransportType:          0x03,
+			CommandSpecific:        0x55,
+			TransportTypeSpecific:  0x66,
+		},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("DecodeErrorLog() returned %d entries, want %d", len(got), len(want))
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("DecodeErrorLog() entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDecodeFirmwareSlotLog(t *testing.T) {
+	t.Parallel()
+
+	data := make([]byte, logpage.FirmwareSlotLogSize)
+	// Slot 2 active, slot 3 activated at the next reset
+	data[0] = 0x3<<4 | 0x2
+	copy(data[8:], "1.0.0   ")
+	copy(data[16:], "GPJA0B3Q")
+	copy(data[24:], "2.0\x00\x00\x00\x00\x00")
+
+	got, err := logpage.DecodeFirmwareSlotLog(data)
+	if err != nil {
+		t.Fatalf("DecodeFirmwareSlotLog() error = %v", err)
+	}
+
+	want := &logpage.FirmwareSlotLog{
+		ActiveSlot:    2,
+		NextResetSlot: 3,
+		Revisions:     [logpage.FirmwareSlots]string{"1.0.0", "GPJA0B3Q", "2.0"},
+	}
+
+	if *got != *want {
+		t.Errorf("DecodeFirmwareSlotLog() = %+v, want %+v", *got, *want)
+	}
+}
+
+// ocpSmartLogFixture returns an OCP SMART extended log page with every field set at the spec offsets.
+func ocpSmartLogFixture() []byte {
+	data := make([]byte, logpage.OcpSmartLogSize)
+
+	putUint128(data, 0, 0x1000, 0x1)
+	putUint128(data, 16, 0x2000, 0)
+	copy(data[32:], []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06})
+	binary.LittleEndian.PutUint16(data[38:], 98)
+	copy(data[40:], []byte{0x10, 0x00, 0x00, 0x00, 0x00, 0x01})
+	binary.LittleEndian.PutUint16(data[46:], 99)
+	binary.LittleEndian.PutUint64(data[48:], 1)
+	binary.LittleEndian.PutUint64(data[56:], 2)
+	binary.LittleEndian.PutUint64(data[64:], 3)
+	binary.LittleEndian.PutUint32(data[72:], 4)
+	binary.LittleEndian.PutUint32(data[76:], 5)
+	data[80] = 6
+	copy(data[81:], []byte{0x07, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01})
+	binary.LittleEndian.PutUint32(data[88:], 8)
+	binary.LittleEndian.PutUint32(data[92:], 9)
+	data[96] = 10
+	data[97] = 11
+	data[98] = 12
+	binary.LittleEndian.PutUint16(data[99:], 13)
+	binary.LittleEndian.PutUint16(data[101:], 14)
+	data[103] = 2
+	binary.LittleEndian.PutUint64(data[104:], 15)
+	binary.LittleEndian.PutUint32(data[112:], 16)
+	data[120] = 17
+	binary.LittleEndian.PutUint16(data[128:], 18)
+	data[130] = 19
+	data[131] = 20
+	binary.LittleEndian.PutUint64(data[136:], 21)
+	binary.LittleEndian.PutUint64(data[144:], 22)
+	binary.LittleEndian.PutUint64(data[152:], 23)
+	putUint128(data, 160, 24, 0)
+	putUint128(data, 176, 25, 0x2)
+	binary.LittleEndian.PutUint64(data[192:], 26)
+	binary.LittleEndian.PutUint64(data[200:], 27)
+	copy(data[208:], "FW1.2   ")
+	binary.LittleEndian.PutUint16(data[494:], 5)
+	// The GUID is little endian, nvme-cli prints it most significant byte first
+	binary.LittleEndian.PutUint64(data[496:], 0xa4f2bfea2810afc5)
+	binary.LittleEndian.PutUint64(data[504:], 0xafd514c97c6f4f9c)
+
+	return data
+}
+
+func TestDecodeOcpSmartLog(t *testing.T) {
+	t.Parallel()
+
+	got, err := logpage.DecodeOcpSmartLog(ocpSmartLogFixture())
+	if err != nil {
+		t.Fatalf("DecodeOcpSmartLog() error = %v", err)
+	}
+
+	want := &logpage.OcpSmartLog{
+		PhysicalMediaUnitsWritten:       logpage.Uint128{Lo: 0x1000, Hi: 0x1},
+		PhysicalMediaUnitsRead:          logpage.Uint128{Lo: 0x2000},
+		BadUserNANDBlocksRaw:            0x060504030201,
+		BadUserNANDBlocksNormalized:     98,
+		BadSystemNANDBlocksRaw:          0x010000000010,
+		BadSystemNANDBlocksNormalized:   99,
+		XORRecoveryCount:                1,
+		UncorrectableReadErrorCount:     2,
+		SoftECCErrorCount:               3,
+		EndToEndDetectedErrors:          4,
+		EndToEndCorrectedErrors:         5,
+		SystemDataPercentUsed:           6,
+		RefreshCounts:                   0x01000000000007,
+		MaxUserDataEraseCount:           8,
+		MinUserDataEraseCount:           9,
+		ThermalThrottlingEvents:         10,
+		ThermalThrottlingStatus:         11,
+		DSSDErrataVersion:               12,
+		DSSDPointVersion:                13,
+		DSSDMinorVersion:                14,
+		DSSDMajorVersion:                2,
+		PCIeCorrectableErrorCount:       15,
+		IncompleteShutdowns:             16,
+		PercentFreeBlocks:               17,
+		CapacitorHealth:                 18,
+		NVMeBaseErrataVersion:           19,
+		NVMeCommandSetErrataVersion:     20,
+		UnalignedIO:                     21,
+		SecurityVersionNumber:           22,
+		TotalNUSE:                       23,
+		PLPStartCount:                   logpage.Uint128{Lo: 24},
+		EnduranceEstimate:               logpage.Uint128{Lo: 25, Hi: 0x2},
+		PCIeLinkRetrainingCount:         26,
+		PowerStateChangeCount:           27,
+		LowestPermittedFirmwareRevision: "FW1.2",
+		LogPageVersion:                  5,
+		LogPageGUID:                     logpage.OcpSmartLogGUID,
+	}
+
+	if *got != *want {
+		t.Errorf("DecodeOcpSmartLog() = %+v, want %+v", *got, *want)
+	}
+}
+
+func TestDecodeOcpSmartLogGUIDMismatch(t *testing.T) {
+	t.Parallel()
+
+	data := ocpSmartLogFixture()
+	// A vendor using C0h for another page
+	binary.LittleEndian.PutUint64(data[504:], 0)
+
+	_, err := logpage.DecodeOcpSmartLog(data)
+	if !errors.Is(err, logpage.ErrGUIDMismatch) {
+		t.Errorf("DecodeOcpSmartLog() error = %v, want %v", err, logpage.ErrGUIDMismatch)
+	}
+}
+
+func TestDecodeShortPage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		decode func([]byte) error
+		size   int
+	}{
+		{
+			name: "SMART log",
+			decode: func(data []byte) error {
+				_, err := logpage.DecodeSmartLog(data)
+
+				return err
+			},
+			size: logpage.SmartLogSize,
+		},
+		{
+			name: "Error Information log",
+			decode: func(data []byte) error {
+				_, err := logpage.DecodeErrorLog(data)
+
+				return err
+			},
+			size: logpage.ErrorLogEntrySize,
+		},
+		{
+			name: "Firmware Slot log",
+			decode: func(data []byte) error {
+				_, err := logpage.DecodeFirmwareSlotLog(data)
+
+				return err
+			},
+			size: logpage.FirmwareSlotLogSize,
+		},
+		{
+			name: "OCP SMART log",
+			decode: func(data []byte) error {
+				_, err := logpage.DecodeOcpSmartLog(data)
+
+				return err
+			},
+			size: logpage.OcpSmartLogSize,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, size := range []int{0, test.size - 1} {
+				err := test.decode(make([]byte, size))
+				if !errors.Is(err, logpage.ErrShortPage) {
+					t.Errorf("decoding %d bytes: error = %v, want %v", size, err, logpage.ErrShortPage)
+				}
+			}
+
+			if err := test.decode(make([]byte, test.size)); errors.Is(err, logpage.ErrShortPage) {
+				t.Errorf("decoding %d bytes: error = %v, want no short page error", test.size, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package logpage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// OcpSmartLogSize is the size of the OCP SMART / Health Information Extended log page.
+const OcpSmartLogSize = 512
+
+// OcpSmartLogGUID is the Log Page GUID identifying the OCP C0h page,
+// formatted most significant byte first like nvme-cli does.
+const OcpSmartLogGUID = "0xafd514c97c6f4f9ca4f2bfea2810afc5"
+
+// ErrGUIDMismatch is returned when a vendor specific log page
+// does not carry the GUID defined by the OCP specification.
+var ErrGUIDMismatch = errors.New("log page GUID mismatch")
+
+// OcpSmartLog is the OCP SMART / Health Information Extended log page (C0h).
+type OcpSmartLog struct {
+	// PhysicalMediaUnitsWritten and PhysicalMediaUnitsRead are in bytes
+	PhysicalMediaUnitsWritten Uint128
+	PhysicalMediaUnitsRead    Uint128
+
+	// Raw counts are 6 bytes fields, normalized values are 0-100
+	BadUserNANDBlocksRaw          uint64
+	BadUserNANDBlocksNormalized   uint16
+	BadSystemNANDBlocksRaw        uint64
+	BadSystemNANDBlocksNormalized uint16
+
+	XORRecoveryCount            uint64
+	UncorrectableReadErrorCount uint64
+	SoftECCErrorCount           uint64
+	EndToEndDetectedErrors      uint32
+	EndToEndCorrectedErrors     uint32
+	SystemDataPercentUsed       uint8
+	// RefreshCounts is a 7 bytes field
+	RefreshCounts                   uint64
+	MaxUserDataEraseCount           uint32
+	MinUserDataEraseCount           uint32
+	ThermalThrottlingEvents         uint8
+	ThermalThrottlingStatus         uint8
+	DSSDErrataVersion               uint8
+	DSSDPointVersion                uint16
+	DSSDMinorVersion                uint16
+	DSSDMajorVersion                uint8
+	PCIeCorrectableErrorCount       uint64
+	IncompleteShutdowns             uint32
+	PercentFreeBlocks               uint8
+	CapacitorHealth                 uint16 // named PLP Health since OCP 2.5
+	NVMeBaseErrataVersion           uint8
+	NVMeCommandSetErrataVersion     uint8
+	UnalignedIO                     uint64
+	SecurityVersionNumber           uint64
+	TotalNUSE                       uint64
+	PLPStartCount                   Uint128
+	EnduranceEstimate               Uint128
+	PCIeLinkRetrainingCount         uint64
+	PowerStateChangeCount           uint64
+	LowestPermittedFirmwareRevision string
+	LogPageVersion                  uint16
+	// LogPageGUID is formatted most significant byte first (see OcpSmartLogGUID)
+	LogPageGUID string
+}
+
+// DecodeOcpSmartLog decodes the OCP SMART / Health Information Extended log page (C0h).
+// Since C0h is a vendor specific log identifier, the Log Page GUID is checked
+// to tell OCP compliant drives apart from drives using C0h for other purposes,
+// returning ErrGUIDMismatch if it does not match.
+func DecodeOcpSmartLog(data []byte) (*OcpSmartLog, error) {
+	err := checkSize("OCP SMART log", data, OcpSmartLogSize)
+	if err != nil {
+		return nil, err
+	}
+
+	guid := guidString(data, 496)
+	if guid != OcpSmartLogGUID {
+		return nil, fmt.Errorf("%w: %s is not the OCP SMART extended log GUID", ErrGUIDMismatch, guid)
+	}
+
+	return &OcpSmartLog{
+		PhysicalMediaUnitsWritten:       le128(data, 0),
+		PhysicalMediaUnitsRead:          le128(data, 16),
+		BadUserNANDBlocksRaw:            leN(data, 32, 6),
+		BadUserNANDBlocksNormalized:     le16(data, 38),
+		BadSystemNANDBlocksRaw:          leN(data, 40, 6),
+		BadSystemNANDBlocksNormalized:   le16(data, 46),
+		XORRecoveryCount:                le64(data, 48),
+		UncorrectableReadErrorCount:     le64(data, 56),
+		SoftECCErrorCount:               le64(data, 64),
+		EndToEndDetectedErrors:          le32(data, 72),
+		EndToEndCorrectedErrors:         le32(data, 76),
+		SystemDataPercentUsed:           data[80],
+		RefreshCounts:                   leN(data, 81, 7),
+		MaxUserDataEraseCount:           le32(data, 88),
+		MinUserDataEraseCount:           le32(data, 92),
+		ThermalThrottlingEvents:         data[96],
+		ThermalThrottlingStatus:         data[97],
+		DSSDErrataVersion:               data[98],
+		DSSDPointVersion:                le16(data, 99),
+		DSSDMinorVersion:                le16(data, 101),
+		DSSDMajorVersion:                data[103],
+		PCIeCorrectableErrorCount:       le64(data, 104),
+		IncompleteShutdowns:             le32(data, 112),
+		PercentFreeBlocks:               data[120],
+		CapacitorHealth:                 le16(data, 128),
+		NVMeBaseErrataVersion:           data[130],
+		NVMeCommandSetErrataVersion:     data[131],
+		UnalignedIO:                     le64(data, 136),
+		SecurityVersionNumber:           le64(data, 144),
+		TotalNUSE:                       le64(data, 152),
+		PLPStartCount:                   le128(data, 160),
+		EnduranceEstimate:               le128(data, 176),
+		PCIeLinkRetrainingCount:         le64(data, 192),
+		PowerStateChangeCount:           le64(data, 200),
+		LowestPermittedFirmwareRevision: ascii(data, 208, 8),
+		LogPageVersion:                  le16(data, 494),
+		LogPageGUID:                     guid,
+	}, nil
+}
+
+// guidString formats a 16 bytes little endian GUID field as an hex string.
+func guidString(data []byte, offset int) string {
+	return fmt.Sprintf("0x%016x%016x", le64(data, offset+8), le64(data, offset))
+}
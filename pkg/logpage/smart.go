@@ -0,0 +1,87 @@
+package logpage
+
+// SmartLogSize is the size of the SMART / Health Information log page.
+const SmartLogSize = 512
+
+// SmartTemperatureSensors is the number of temperature sensor fields in the SMART log.
+const SmartTemperatureSensors = 8
+
+// SmartLog is the SMART / Health Information log page (02h).
+type SmartLog struct {
+	// CriticalWarning bits indicate spare capacity, temperature,
+	// degraded reliability, read-only mode, volatile memory backup and PMR failures
+	CriticalWarning uint8
+	// CompositeTemperature is the current composite temperature in Kelvin
+	CompositeTemperature uint16
+	// AvailableSpare is the normalized percentage (0-100) of remaining spare capacity
+	AvailableSpare uint8
+	// AvailableSpareThreshold is the threshold below which an asynchronous event is generated
+	AvailableSpareThreshold uint8
+	// PercentageUsed is the vendor specific estimate of the device life used (0-255)
+	PercentageUsed uint8
+	// EnduranceGroupCriticalWarningSummary is the OR of the critical warnings of all endurance groups
+	EnduranceGroupCriticalWarningSummary uint8
+
+	// DataUnitsRead and DataUnitsWritten are in thousands of 512-byte units
+	DataUnitsRead    Uint128
+	DataUnitsWritten Uint128
+
+	HostReadCommands   Uint128
+	HostWriteCommands  Uint128
+	ControllerBusyTime Uint128 // minutes
+	PowerCycles        Uint128
+	PowerOnHours       Uint128
+	UnsafeShutdowns    Uint128
+	MediaErrors        Uint128
+	ErrorLogEntries    Uint128
+
+	WarningCompositeTemperatureTime  uint32 // minutes
+	CriticalCompositeTemperatureTime uint32 // minutes
+
+	// TemperatureSensors are in Kelvin, 0 means the sensor is not implemented
+	TemperatureSensors [SmartTemperatureSensors]uint16
+
+	ThermalManagementTemp1TransitionCount uint32
+	ThermalManagementTemp2TransitionCount uint32
+	ThermalManagementTemp1TotalTime       uint32 // seconds
+	ThermalManagementTemp2TotalTime       uint32 // seconds
+}
+
+// DecodeSmartLog decodes the SMART / Health Information log page (02h).
+func DecodeSmartLog(data []byte) (*SmartLog, error) {
+	err := checkSize("SMART log", data, SmartLogSize)
+	if err != nil {
+		return nil, err
+	}
+
+	smartLog := &SmartLog{
+		CriticalWarning:                       data[0],
+		CompositeTemperature:                  le16(data, 1),
+		AvailableSpare:                        data[3],
+		AvailableSpareThreshold:               data[4],
+		PercentageUsed:                        data[5],
+		EnduranceGroupCriticalWarningSummary:  data[6],
+		DataUnitsRead:                         le128(data, 32),
+		DataUnitsWritten:                      le128(data, 48),
+		HostReadCommands:                      le128(data, 64),
+		HostWriteCommands:                     le128(data, 80),
+		ControllerBusyTime:                    le128(data, 96),
+		PowerCycles:                           le128(data, 112),
+		PowerOnHours:                          le128(data, 128),
+		UnsafeShutdowns:                       le128(data, 144),
+		MediaErrors:                           le128(data, 160),
+		ErrorLogEntries:                       le128(data, 176),
+		WarningCompositeTemperatureTime:       le32(data, 192),
+		CriticalCompositeTemperatureTime:      le32(data, 196),
+		ThermalManagementTemp1TransitionCount: le32(data, 216),
+		ThermalManagementTemp2TransitionCount: le32(data, 220),
+		ThermalManagementTemp1TotalTime:       le32(data, 224),
+		ThermalManagementTemp2TotalTime:       le32(data, 228),
+	}
+
+	for i := range smartLog.TemperatureSensors {
+		smartLog.TemperatureSensors[i] = le16(data, 200+2*i)
+	}
+
+	return smartLog, nil
+}
@@ -0,0 +1,154 @@
+package pkg
+
+import (
+	"path/filepath"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// anaStates are the Asymmetric Namespace Access states reported by the kernel and nvme-cli.
+var anaStates = []string{"optimized", "non-optimized", "inaccessible", "persistent-loss", "change"}
+
+// ioPolicies are the native multipath I/O policies of the subsystems.
+var ioPolicies = []string{"numa", "round-robin", "queue-depth"}
+
+// MultipathMetricCollector implements SubsystemMetricCollector and ControllerMetricCollector,
+// and sends the native multipath metrics: the ANA state of each path from sysfs,
+// the ANA group states from the ANA log page, and the subsystem I/O policy.
+type MultipathMetricCollector struct {
+	sysfsRoot string
+
+	// getAnaLog receives the controller device path and gets the ANA log JSON data,
+	// nil if the backend cannot read the ANA log page
+	getAnaLog func(string) gjson.Result
+
+	pathStateDesc       *prometheus.Desc
+	groupStateDesc      *prometheus.Desc
+	groupNamespacesDesc *prometheus.Desc
+	ioPolicyDesc        *prometheus.Desc
+}
+
+// NewMultipathMetricCollector initializes and returns a new MultipathMetricCollector object.
+func NewMultipathMetricCollector(sysfsRoot string, getAnaLog func(string) gjson.Result) *MultipathMetricCollector {
+	return &MultipathMetricCollector{
+		sysfsRoot: sysfsRoot,
+		getAnaLog: getAnaLog,
+		pathStateDesc: prometheus.NewDesc(
+			"nvme_path_ana_state",
+			"ANA state of the multipath namespace path, 1 for the current state and 0 for the others",
+			[]string{"path", "controller", "state"},
+			nil,
+		),
+		groupStateDesc: prometheus.NewDesc(
+			"nvme_ana_group_state",
+			"ANA group state reported by the controller ANA log, 1 for the current state and 0 for the others",
+			[]string{"controller", "group", "state"},
+			nil,
+		),
+		groupNamespacesDesc: prometheus.NewDesc(
+			"nvme_ana_group_namespaces",
+			"Number of namespaces in the ANA group",
+			[]string{"controller", "group"},
+			nil,
+		),
+		ioPolicyDesc: prometheus.NewDesc(
+			"nvme_subsystem_iopolicy",
+			"Native multipath I/O policy of the subsystem, 1 for the current policy and 0 for the others",
+			[]string{"subsystem", "iopolicy"},
+			nil,
+		),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (mc *MultipathMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- mc.pathStateDesc
+	ch <- mc.groupStateDesc
+	ch <- mc.groupNamespacesDesc
+	ch <- mc.ioPolicyDesc
+}
+
+// CollectSubsystemMetrics reads the subsystem I/O policy and sends it through the channel.
+func (mc *MultipathMetricCollector) CollectSubsystemMetrics(ch chan<- prometheus.Metric, subsystem *Subsystem) error {
+	if subsystem.Name == "" {
+		return nil
+	}
+
+	ioPolicy := readSysfsStringOrEmpty(
+		filepath.Join(mc.sysfsRoot, "class", "nvme-subsystem", subsystem.Name, "iopolicy"),
+	)
+	if ioPolicy == "" {
+		return nil
+	}
+
+	sendStateSet(ch, mc.ioPolicyDesc, ioPolicies, ioPolicy, subsystem.Name)
+
+	return nil
+}
+
+// CollectControllerMetrics sends the ANA state of the controller paths and ANA groups
+// through the channel. Controllers without multipath paths are skipped.
+func (mc *MultipathMetricCollector) CollectControllerMetrics(
+	ch chan<- prometheus.Metric,
+	controller *Controller,
+) error {
+	if len(controller.Paths) == 0 {
+		return nil
+	}
+
+	for _, path := range controller.Paths {
+		anaState := readSysfsStringOrEmpty(filepath.Join(mc.sysfsRoot, "block", path.Name, "ana_state"))
+		if anaState == "" {
+			continue
+		}
+
+		sendStateSet(ch, mc.pathStateDesc, anaStates, anaState, path.Name, controller.Name)
+	}
+
+	if mc.getAnaLog == nil {
+		return nil
+	}
+
+	anaLog := mc.getAnaLog("/dev/" + controller.Name)
+	if !anaLog.Exists() {
+		return noDataError("/dev/"+controller.Name, anaLog)
+	}
+
+	// nvme-cli names the descriptors array with a trailing space
+	for _, group := range anaLog.Get("ANA DESC LIST ").Array() {
+		groupID := group.Get("grpid").String()
+
+		sendStateSet(ch, mc.groupStateDesc, anaStates, group.Get("state").String(), controller.Name, groupID)
+
+		ch <- prometheus.MustNewConstMetric(
+			mc.groupNamespacesDesc,
+			prometheus.GaugeValue,
+			group.Get("nnsids").Float(),
+			controller.Name,
+			groupID,
+		)
+	}
+
+	return nil
+}
+
+// sendStateSet sends a metric for every known state, with value 1 for the current state
+// and 0 for the others, like the node_exporter systemd unit state metrics.
+// The state is appended to the labels as the last label value.
+func sendStateSet(
+	ch chan<- prometheus.Metric,
+	desc *prometheus.Desc,
+	states []string,
+	current string,
+	labels ...string,
+) {
+	for _, state := range states {
+		value := 0.0
+		if state == current {
+			value = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, append(labels, state)...)
+	}
+}
@@ -0,0 +1,81 @@
+package pkg_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+func TestMultipathMetricCollector(t *testing.T) {
+	t.Parallel()
+
+	sysfsRoot := t.TempDir()
+
+	writeSysfsAttributes(t, filepath.Join(sysfsRoot, "class", "nvme-subsystem", "nvme-subsys1"), map[string]string{
+		"iopolicy": "round-robin",
+	})
+
+	for path, state := range map[string]string{
+		"nvme1c1n1": "optimized",
+		"nvme1c2n1": "non-optimized",
+		"nvme1c2n2": "inaccessible",
+	} {
+		writeSysfsAttributes(t, filepath.Join(sysfsRoot, "block", path), map[string]string{"ana_state": state})
+	}
+
+	getAnaLog := func(devicePath string) gjson.Result {
+		if devicePath != "/dev/nvme2" {
+			return pkg.SkippedResult()
+		}
+
+		// nvme-cli names the descriptors array with a trailing space
+		return gjson.Parse(`{
+			"ANA DESC LIST ": [
+				{"grpid": 1, "nnsids": 1, "chgcnt": 0, "state": "non-optimized", "NSIDS": [{"nsid": 1}]},
+				{"grpid": 2, "nnsids": 1, "chgcnt": 0, "state": "persistent-loss", "NSIDS": [{"nsid": 2}]}
+			]
+		}`)
+	}
+
+	collector := newTestCollector("multipath", pkg.NewMultipathMetricCollector(sysfsRoot, getAnaLog),
+		newMultipathTestSubsystem())
+
+	values := gatherValues(t, collector)
+
+	// Every state of the set is exported, 1 for the current state and 0 for the others
+	expected := map[string]float64{}
+	for _, state := range []string{"optimized", "non-optimized", "inaccessible", "persistent-loss", "change"} {
+		expected[`nvme_path_ana_state{controller="nvme1",path="nvme1c1n1",state="`+state+`"}`] = 0
+		expected[`nvme_path_ana_state{controller="nvme2",path="nvme1c2n1",state="`+state+`"}`] = 0
+		expected[`nvme_path_ana_state{controller="nvme2",path="nvme1c2n2",state="`+state+`"}`] = 0
+		expected[`nvme_ana_group_state{controller="nvme2",group="1",state="`+state+`"}`] = 0
+		expected[`nvme_ana_group_state{controller="nvme2",group="2",state="`+state+`"}`] = 0
+	}
+
+	expected[`nvme_path_ana_state{controller="nvme1",path="nvme1c1n1",state="optimized"}`] = 1
+	expected[`nvme_path_ana_state{controller="nvme2",path="nvme1c2n1",state="non-optimized"}`] = 1
+	expected[`nvme_path_ana_state{controller="nvme2",path="nvme1c2n2",state="inaccessible"}`] = 1
+	expected[`nvme_ana_group_state{controller="nvme2",group="1",state="non-optimized"}`] = 1
+	expected[`nvme_ana_group_state{controller="nvme2",group="2",state="persistent-loss"}`] = 1
+	expected[`nvme_ana_group_namespaces{controller="nvme2",group="1"}`] = 1
+	expected[`nvme_ana_group_namespaces{controller="nvme2",group="2"}`] = 1
+
+	for _, policy := range []string{"numa", "round-robin", "queue-depth"} {
+		expected[`nvme_subsystem_iopolicy{iopolicy="`+policy+`",subsystem="nvme-subsys1"}`] = 0
+	}
+
+	expected[`nvme_subsystem_iopolicy{iopolicy="round-robin",subsystem="nvme-subsys1"}`] = 1
+
+	// The skipped ANA log is not a failure
+	expected[`nvme_exporter_collector_success{collector="multipath",device="/dev/nvme1"}`] = 1
+
+	checkValues(t, values, expected)
+
+	// The ANA groups are not exported for the controller whose ANA log is skipped
+	if _, found := values[`nvme_ana_group_namespaces{controller="nvme1",group="1"}`]; found {
+		t.Error("the ANA groups of nvme1 are exported without an ANA log")
+	}
+}
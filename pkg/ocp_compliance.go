@@ -0,0 +1,178 @@
+package pkg
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// ocpUnsupportedRequirementRegex matches the entries of the OCP unsupported requirements log
+// (e.g. "Unsupported Requirement List 0").
+var ocpUnsupportedRequirementRegex = regexp.MustCompile(`^Unsupported Requirement List \d+$`)
+
+// nonAlphanumericRegex matches the characters to replace when turning a JSON key into a label value.
+var nonAlphanumericRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ocpLogHeaderKeys are the keys shared by the OCP log pages that do not describe the device.
+var ocpLogHeaderKeys = map[string]bool{
+	"Log Page Version": true,
+	"Log page GUID":    true,
+}
+
+// OcpDeviceCapabilityMetricCollector implements ControllerMetricCollector and sends
+// the capability fields of the OCP Device Capabilities log page (C4h) of each controller.
+type OcpDeviceCapabilityMetricCollector struct {
+	// getData receives the controller device path and gets the device capabilities log JSON data
+	getData func(string) gjson.Result
+
+	capabilityDesc *prometheus.Desc
+}
+
+// NewOcpDeviceCapabilityMetricCollector initializes and returns a new OcpDeviceCapabilityMetricCollector object.
+func NewOcpDeviceCapabilityMetricCollector(getData func(string) gjson.Result) *OcpDeviceCapabilityMetricCollector {
+	return &OcpDeviceCapabilityMetricCollector{
+		getData: getData,
+		capabilityDesc: prometheus.NewDesc(
+			"nvme_ocp_device_capability",
+			"Capability field reported by the OCP device capabilities log, "+
+				"0 if not supported or a field specific bitmask or count otherwise",
+			[]string{"controller", "capability"},
+			nil,
+		),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (oc *OcpDeviceCapabilityMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- oc.capabilityDesc
+}
+
+// CollectControllerMetrics gets the device capabilities log data of the controller
+// and sends a metric for each numeric capability field through the channel.
+func (oc *OcpDeviceCapabilityMetricCollector) CollectControllerMetrics(
+	ch chan<- prometheus.Metric,
+	controller *Controller,
+) error {
+	if controller.Name == "" {
+		return nil
+	}
+
+	devicePath := "/dev/" + controller.Name
+
+	jsonData := oc.getData(devicePath)
+
+	// If getData returns invalid data (e.g., device capabilities log not supported), skip this collector
+	if !jsonData.Exists() {
+		return noDataError(devicePath, jsonData)
+	}
+
+	jsonData.ForEach(func(key, value gjson.Result) bool {
+		// Descriptors such as the DSSD power states are not flags and are skipped
+		if ocpLogHeaderKeys[key.String()] || value.Type != gjson.Number {
+			return true
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			oc.capabilityDesc,
+			prometheus.GaugeValue,
+			value.Float(),
+			controller.Name,
+			toSnakeCase(key.String()),
+		)
+
+		return true
+	})
+
+	return nil
+}
+
+// OcpUnsupportedRequirementsMetricCollector implements ControllerMetricCollector and sends
+// the OCP Datacenter NVMe SSD specification requirements the device does not comply with,
+// as reported by the OCP Unsupported Requirements log page (C5h) of each controller.
+type OcpUnsupportedRequirementsMetricCollector struct {
+	// getData receives the controller device path and gets the unsupported requirements log JSON data
+	getData func(string) gjson.Result
+
+	requirementDesc  *prometheus.Desc
+	requirementsDesc *prometheus.Desc
+}
+
+// NewOcpUnsupportedRequirementsMetricCollector initializes and returns
+// a new OcpUnsupportedRequirementsMetricCollector object.
+func NewOcpUnsupportedRequirementsMetricCollector(
+	getData func(string) gjson.Result,
+) *OcpUnsupportedRequirementsMetricCollector {
+	return &OcpUnsupportedRequirementsMetricCollector{
+		getData: getData,
+		requirementDesc: prometheus.NewDesc(
+			"nvme_ocp_unsupported_requirement_info",
+			"OCP specification requirement not supported by the device, value is always 1",
+			[]string{"controller", "requirement"},
+			nil,
+		),
+		requirementsDesc: prometheus.NewDesc(
+			"nvme_ocp_unsupported_requirements",
+			"Number of OCP specification requirements not supported by the device",
+			[]string{"controller"},
+			nil,
+		),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (oc *OcpUnsupportedRequirementsMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- oc.requirementDesc
+	ch <- oc.requirementsDesc
+}
+
+// CollectControllerMetrics gets the unsupported requirements log data of the controller
+// and sends the unsupported requirement identifiers through the channel.
+func (oc *OcpUnsupportedRequirementsMetricCollector) CollectControllerMetrics(
+	ch chan<- prometheus.Metric,
+	controller *Controller,
+) error {
+	if controller.Name == "" {
+		return nil
+	}
+
+	devicePath := "/dev/" + controller.Name
+
+	jsonData := oc.getData(devicePath)
+
+	// If getData returns invalid data (e.g., unsupported requirements log not supported), skip this collector
+	if !jsonData.Exists() {
+		return noDataError(devicePath, jsonData)
+	}
+
+	requirements := make(map[string]bool)
+
+	jsonData.ForEach(func(key, value gjson.Result) bool {
+		requirement := strings.TrimSpace(value.String())
+		if ocpUnsupportedRequirementRegex.MatchString(key.String()) && requirement != "" {
+			requirements[requirement] = true
+		}
+
+		return true
+	})
+
+	for requirement := range requirements {
+		ch <- prometheus.MustNewConstMetric(oc.requirementDesc, prometheus.GaugeValue, 1, controller.Name, requirement)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		oc.requirementsDesc,
+		prometheus.GaugeValue,
+		float64(len(requirements)),
+		controller.Name,
+	)
+
+	return nil
+}
+
+// toSnakeCase turns a nvme-cli JSON key into a label value
+// (e.g. "Write Zeroes Command Support" into "write_zeroes_command_support").
+func toSnakeCase(key string) string {
+	return strings.Trim(nonAlphanumericRegex.ReplaceAllString(strings.ToLower(key), "_"), "_")
+}
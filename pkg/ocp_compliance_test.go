@@ -0,0 +1,62 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+func TestOcpComplianceMetricCollectors(t *testing.T) {
+	t.Parallel()
+
+	getCapabilityData := func(string) gjson.Result {
+		return gjson.Parse(`{
+			"Log Page Version": 1,
+			"Log page GUID": "0xb7053c914b58495d98c9e1d10d054297",
+			"Write Zeroes Command Support": 7,
+			"OOB Management Support": 0,
+			"DSSD Power State Descriptors": [1, 2]
+		}`)
+	}
+
+	getRequirementsData := func(string) gjson.Result {
+		return gjson.Parse(`{
+			"Number Unsupported Req IDs": 3,
+			"Unsupported Requirement List 0": "SEC-3",
+			"Unsupported Requirement List 1": "PLP-2 ",
+			"Unsupported Requirement List 2": "SEC-3",
+			"Unsupported Requirement List 3": ""
+		}`)
+	}
+
+	collector := pkg.NewCompositeCollector(
+		[]pkg.NamedMetricCollector{
+			pkg.NewNamedMetricCollector("ocp_capabilities", pkg.NewOcpDeviceCapabilityMetricCollector(getCapabilityData)),
+			pkg.NewNamedMetricCollector("ocp_unsupported",
+				pkg.NewOcpUnsupportedRequirementsMetricCollector(getRequirementsData)),
+		},
+		func() []*pkg.Subsystem {
+			return []*pkg.Subsystem{newMultipathTestSubsystem()}
+		},
+	)
+
+	values := gatherValues(t, collector)
+
+	checkValues(t, values, map[string]float64{
+		`nvme_ocp_device_capability{capability="write_zeroes_command_support",controller="nvme1"}`: 7,
+		`nvme_ocp_device_capability{capability="oob_management_support",controller="nvme2"}`:       0,
+		// The duplicate and empty requirements are skipped
+		`nvme_ocp_unsupported_requirement_info{controller="nvme1",requirement="SEC-3"}`: 1,
+		`nvme_ocp_unsupported_requirement_info{controller="nvme1",requirement="PLP-2"}`: 1,
+		`nvme_ocp_unsupported_requirements{controller="nvme2"}`:                         2,
+	})
+
+	// The header fields and the descriptors are not capabilities
+	for _, capability := range []string{"log_page_version", "dssd_power_state_descriptors"} {
+		if _, found := values[`nvme_ocp_device_capability{capability="`+capability+`",controller="nvme1"}`]; found {
+			t.Errorf("%s is exported as a capability", capability)
+		}
+	}
+}
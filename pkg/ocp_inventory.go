@@ -0,0 +1,220 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// ocpHardwareComponentIDKey is the key identifying a component descriptor
+// in the OCP hardware component log JSON.
+const ocpHardwareComponentIDKey = "Identifier"
+
+// OcpHardwareComponentMetricCollector implements ControllerMetricCollector and sends
+// the component inventory of the OCP Hardware Component log page of each controller (e.g. the
+// controller ASIC, the NAND packages and the capacitors), with the manufacturer, revision
+// and date/lot codes.
+type OcpHardwareComponentMetricCollector struct {
+	// getData receives the controller device path and gets the hardware component log JSON data
+	getData func(string) gjson.Result
+
+	componentDesc *prometheus.Desc
+}
+
+// NewOcpHardwareComponentMetricCollector initializes and returns a new OcpHardwareComponentMetricCollector object.
+func NewOcpHardwareComponentMetricCollector(getData func(string) gjson.Result) *OcpHardwareComponentMetricCollector {
+	return &OcpHardwareComponentMetricCollector{
+		getData: getData,
+		componentDesc: prometheus.NewDesc(
+			"nvme_ocp_hardware_component_info",
+			"Hardware component reported by the OCP hardware component log, value is always 1",
+			[]string{
+				"controller",
+				"component",
+				"identifier",
+				"manufacturer",
+				"revision",
+				"manufacturer_code",
+				"date_lot_code",
+			},
+			nil,
+		),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (oc *OcpHardwareComponentMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- oc.componentDesc
+}
+
+// CollectControllerMetrics gets the hardware component log data of the controller
+// and sends an info metric for each component descriptor through the channel.
+func (oc *OcpHardwareComponentMetricCollector) CollectControllerMetrics(
+	ch chan<- prometheus.Metric,
+	controller *Controller,
+) error {
+	if controller.Name == "" {
+		return nil
+	}
+
+	devicePath := "/dev/" + controller.Name
+
+	jsonData := oc.getData(devicePath)
+
+	// If getData returns invalid data (e.g., hardware component log not supported), skip this collector
+	if !jsonData.Exists() {
+		return noDataError(devicePath, jsonData)
+	}
+
+	seen := make(map[string]bool)
+
+	for _, component := range findOcpHardwareComponents(jsonData) {
+		labels := []string{
+			controller.Name,
+			jsonLabelValue(component.Get("Description")),
+			jsonLabelValue(component.Get(ocpHardwareComponentIDKey)),
+			jsonLabelValue(component.Get("Manufacture")),
+			jsonLabelValue(component.Get("Revision")),
+			jsonLabelValue(component.Get("Manufacture Code")),
+			jsonLabelValue(component.Get("Date/Lot Code")),
+		}
+
+		// Identical components (e.g. NAND packages of the same lot) would be duplicate series
+		key := strings.Join(labels, "\x00")
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+
+		ch <- prometheus.MustNewConstMetric(oc.componentDesc, prometheus.GaugeValue, 1, labels...)
+	}
+
+	return nil
+}
+
+// findOcpHardwareComponents returns the component descriptors of the hardware component log.
+// The descriptors are searched through the whole JSON, since their nesting
+// differs across nvme-cli versions.
+func findOcpHardwareComponents(data gjson.Result) []gjson.Result {
+	if data.IsObject() && data.Get(ocpHardwareComponentIDKey).Exists() {
+		return []gjson.Result{data}
+	}
+
+	var components []gjson.Result
+
+	if data.IsObject() || data.IsArray() {
+		data.ForEach(func(_, value gjson.Result) bool {
+			components = append(components, findOcpHardwareComponents(value)...)
+
+			return true
+		})
+	}
+
+	return components
+}
+
+// OcpTelemetryStringMetricCollector implements ControllerMetricCollector and sends
+// the metrics of the OCP Telemetry String log page (C9h) of each controller, which maps
+// the identifiers of the telemetry statistics and events to their names for the running firmware.
+type OcpTelemetryStringMetricCollector struct {
+	// getData receives the controller device path and gets the telemetry string log JSON data
+	getData func(string) gjson.Result
+
+	infoDesc    *prometheus.Desc
+	entriesDesc *prometheus.Desc
+}
+
+// NewOcpTelemetryStringMetricCollector initializes and returns a new OcpTelemetryStringMetricCollector object.
+func NewOcpTelemetryStringMetricCollector(getData func(string) gjson.Result) *OcpTelemetryStringMetricCollector {
+	return &OcpTelemetryStringMetricCollector{
+		getData: getData,
+		infoDesc: prometheus.NewDesc(
+			"nvme_ocp_telemetry_string_log_info",
+			"OCP telemetry string log page version information, value is always 1",
+			[]string{"controller", "log_page_version", "log_page_guid"},
+			nil,
+		),
+		entriesDesc: prometheus.NewDesc(
+			"nvme_ocp_telemetry_string_entries",
+			"Number of entries of the OCP telemetry string log table",
+			[]string{"controller", "table"},
+			nil,
+		),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (oc *OcpTelemetryStringMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- oc.infoDesc
+	ch <- oc.entriesDesc
+}
+
+// CollectControllerMetrics gets the telemetry string log data of the controller and sends
+// the version information and the size of each string table through the channel.
+func (oc *OcpTelemetryStringMetricCollector) CollectControllerMetrics(
+	ch chan<- prometheus.Metric,
+	controller *Controller,
+) error {
+	if controller.Name == "" {
+		return nil
+	}
+
+	devicePath := "/dev/" + controller.Name
+
+	jsonData := oc.getData(devicePath)
+
+	// If getData returns invalid data (e.g., telemetry string log not supported), skip this collector
+	if !jsonData.Exists() {
+		return noDataError(devicePath, jsonData)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		oc.infoDesc,
+		prometheus.GaugeValue,
+		1,
+		controller.Name,
+		jsonData.Get("Log Page Version").String(),
+		strings.ToLower(jsonData.Get("Log page GUID").String()),
+	)
+
+	jsonData.ForEach(func(key, value gjson.Result) bool {
+		if !value.IsArray() {
+			return true
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			oc.entriesDesc,
+			prometheus.GaugeValue,
+			float64(len(value.Array())),
+			controller.Name,
+			toSnakeCase(key.String()),
+		)
+
+		return true
+	})
+
+	return nil
+}
+
+// jsonLabelValue turns a JSON field into a label value.
+// Byte arrays (e.g. the date/lot codes) are turned into hex strings.
+func jsonLabelValue(value gjson.Result) string {
+	if !value.IsArray() {
+		return strings.TrimSpace(value.String())
+	}
+
+	var builder strings.Builder
+
+	for _, element := range value.Array() {
+		if element.Type == gjson.Number {
+			fmt.Fprintf(&builder, "%02x", element.Uint())
+		} else {
+			builder.WriteString(element.String())
+		}
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,80 @@
+package pkg_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+func TestOcpInventoryMetricCollectors(t *testing.T) {
+	t.Parallel()
+
+	subsystem := &pkg.Subsystem{Name: "nvme-subsys0"}
+	controller := &pkg.Controller{Name: "nvme0", Subsystem: subsystem}
+	controller.Namespaces = []*pkg.Namespace{
+		{Name: "nvme0n1", DevicePath: "/dev/nvme0n1", NSID: 1, Controller: controller},
+		{Name: "nvme0n2", DevicePath: "/dev/nvme0n2", NSID: 2, Controller: controller},
+	}
+	subsystem.Controllers = []*pkg.Controller{controller}
+
+	getComponentData := func(string) gjson.Result {
+		// Two identical NAND packages of the same lot
+		return gjson.Parse(`{"Component Descriptions": [
+			{"Description": "ASIC", "Identifier": 1, "Manufacture": "Vendor ", "Revision": "B0",
+				"Manufacture Code": "0x1234", "Date/Lot Code": [32, 35]},
+			{"Description": "NAND", "Identifier": 2, "Manufacture": "Vendor", "Revision": "1",
+				"Manufacture Code": "0x5678", "Date/Lot Code": [1, 2]},
+			{"Description": "NAND", "Identifier": 2, "Manufacture": "Vendor", "Revision": "1",
+				"Manufacture Code": "0x5678", "Date/Lot Code": [1, 2]}
+		]}`)
+	}
+
+	getStringData := func(string) gjson.Result {
+		return gjson.Parse(`{
+			"Log Page Version": 1,
+			"Log page GUID": "0xB13A83691A8F408B9EA495940057AA44",
+			"Statistics Identifier String Table": [{}, {}, {}],
+			"Event String Table": [{}]
+		}`)
+	}
+
+	collector := pkg.NewCompositeCollector(
+		[]pkg.NamedMetricCollector{
+			pkg.NewNamedMetricCollector("ocp_hardware", pkg.NewOcpHardwareComponentMetricCollector(getComponentData)),
+			pkg.NewNamedMetricCollector("ocp_telemetry_strings",
+				pkg.NewOcpTelemetryStringMetricCollector(getStringData)),
+		},
+		func() []*pkg.Subsystem {
+			return []*pkg.Subsystem{subsystem}
+		},
+	)
+
+	values := gatherValues(t, collector)
+
+	checkValues(t, values, map[string]float64{
+		`nvme_ocp_hardware_component_info{component="ASIC",controller="nvme0",date_lot_code="2023",` +
+			`identifier="1",manufacturer="Vendor",manufacturer_code="0x1234",revision="B0"}`: 1,
+		`nvme_ocp_hardware_component_info{component="NAND",controller="nvme0",date_lot_code="0102",` +
+			`identifier="2",manufacturer="Vendor",manufacturer_code="0x5678",revision="1"}`: 1,
+		`nvme_ocp_telemetry_string_log_info{controller="nvme0",log_page_guid="0xb13a83691a8f408b9ea495940057aa44",` +
+			`log_page_version="1"}`: 1,
+		`nvme_ocp_telemetry_string_entries{controller="nvme0",table="statistics_identifier_string_table"}`: 3,
+		`nvme_ocp_telemetry_string_entries{controller="nvme0",table="event_string_table"}`:                 1,
+	})
+
+	// The logs are exported once per controller, not once per namespace
+	components := 0
+
+	for key := range values {
+		if strings.HasPrefix(key, "nvme_ocp_hardware_component_info{") {
+			components++
+		}
+	}
+
+	if components != 2 {
+		t.Errorf("%d hardware components are exported, want 2", components)
+	}
+}
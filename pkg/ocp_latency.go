@@ -0,0 +1,195 @@
+package pkg
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// ocpLatencyTimestampLayout is the layout of the timestamps printed by nvme-cli
+// in the OCP latency monitor log JSON output.
+const ocpLatencyTimestampLayout = "2006-01-02T15:04:05.000 GMT"
+
+// ocpLatencyBucketRegex matches the per bucket objects of the OCP latency monitor log
+// (e.g. "Active Bucket Counter: Bucket 0").
+var ocpLatencyBucketRegex = regexp.MustCompile(
+	`^(Active|Static) (Bucket Counter|Measured Latency|Latency Time Stamp): Bucket (\d+)$`,
+)
+
+// ocpLatencyThresholdRegex matches the bucket thresholds of the OCP latency monitor log
+// (e.g. "Active Threshold A").
+var ocpLatencyThresholdRegex = regexp.MustCompile(`^Active Threshold ([A-D])$`)
+
+// ocpLatencyOperations maps the command types of the OCP latency monitor log
+// to the operation label values.
+var ocpLatencyOperations = map[string]string{
+	"Read":       "read",
+	"Write":      "write",
+	"Trim":       "deallocate",
+	"Deallocate": "deallocate",
+}
+
+// OcpLatencyMetricCollector implements ControllerMetricCollector and sends the metrics
+// of the OCP Latency Monitor log page (C3h) of each controller: the number of commands
+// in each latency bucket, the maximum latency measured in each bucket and when it was measured.
+type OcpLatencyMetricCollector struct {
+	// getData receives the controller device path and gets the latency monitor log JSON data
+	getData func(string) gjson.Result
+
+	bucketCommandsDesc *prometheus.Desc
+	maxLatencyDesc     *prometheus.Desc
+	maxTimestampDesc   *prometheus.Desc
+	thresholdDesc      *prometheus.Desc
+}
+
+// NewOcpLatencyMetricCollector initializes and returns a new OcpLatencyMetricCollector object.
+func NewOcpLatencyMetricCollector(getData func(string) gjson.Result) *OcpLatencyMetricCollector {
+	labels := []string{"controller", "window", "bucket", "operation"}
+
+	return &OcpLatencyMetricCollector{
+		getData: getData,
+		bucketCommandsDesc: prometheus.NewDesc(
+			"nvme_ocp_latency_bucket_commands",
+			"Number of commands completed with a latency in the OCP latency monitor bucket. "+
+				"The active window counters are reset when the active bucket timer expires",
+			labels,
+			nil,
+		),
+		maxLatencyDesc: prometheus.NewDesc(
+			"nvme_ocp_latency_max_seconds",
+			"Maximum command latency measured in the OCP latency monitor bucket in seconds",
+			labels,
+			nil,
+		),
+		maxTimestampDesc: prometheus.NewDesc(
+			"nvme_ocp_latency_max_timestamp_seconds",
+			"Time when the maximum command latency of the OCP latency monitor bucket was measured, "+
+				"in seconds since the Unix epoch",
+			labels,
+			nil,
+		),
+		thresholdDesc: prometheus.NewDesc(
+			"nvme_ocp_latency_threshold_seconds",
+			"Latency threshold delimiting the active OCP latency monitor buckets in seconds",
+			[]string{"controller", "threshold"},
+			nil,
+		),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (oc *OcpLatencyMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- oc.bucketCommandsDesc
+	ch <- oc.maxLatencyDesc
+	ch <- oc.maxTimestampDesc
+	ch <- oc.thresholdDesc
+}
+
+// CollectControllerMetrics gets the latency monitor log data of the controller
+// and sends the bucket metrics through the channel.
+func (oc *OcpLatencyMetricCollector) CollectControllerMetrics(
+	ch chan<- prometheus.Metric,
+	controller *Controller,
+) error {
+	if controller.Name == "" {
+		return nil
+	}
+
+	devicePath := "/dev/" + controller.Name
+
+	jsonData := oc.getData(devicePath)
+
+	// If getData returns invalid data (e.g., latency monitor not supported), skip this collector
+	if !jsonData.Exists() {
+		return noDataError(devicePath, jsonData)
+	}
+
+	jsonData.ForEach(func(key, value gjson.Result) bool {
+		if match := ocpLatencyThresholdRegex.FindStringSubmatch(key.String()); match != nil {
+			// Thresholds are reported in milliseconds
+			ch <- prometheus.MustNewConstMetric(
+				oc.thresholdDesc,
+				prometheus.GaugeValue,
+				value.Float()/1000,
+				controller.Name,
+				match[1],
+			)
+
+			return true
+		}
+
+		match := ocpLatencyBucketRegex.FindStringSubmatch(key.String())
+		if match == nil {
+			return true
+		}
+
+		window := strings.ToLower(match[1])
+		bucket := match[3]
+
+		value.ForEach(func(operationKey, operationValue gjson.Result) bool {
+			operation, found := ocpLatencyOperations[operationKey.String()]
+			if !found {
+				return true
+			}
+
+			switch match[2] {
+			case "Bucket Counter":
+				ch <- prometheus.MustNewConstMetric(
+					oc.bucketCommandsDesc,
+					prometheus.GaugeValue,
+					operationValue.Float(),
+					controller.Name, window, bucket, operation,
+				)
+			case "Measured Latency":
+				// Latencies are reported in milliseconds
+				ch <- prometheus.MustNewConstMetric(
+					oc.maxLatencyDesc,
+					prometheus.GaugeValue,
+					operationValue.Float()/1000,
+					controller.Name, window, bucket, operation,
+				)
+			case "Latency Time Stamp":
+				timestamp, ok := parseOcpLatencyTimestamp(operationValue)
+				if ok {
+					ch <- prometheus.MustNewConstMetric(
+						oc.maxTimestampDesc,
+						prometheus.GaugeValue,
+						timestamp,
+						controller.Name, window, bucket, operation,
+					)
+				}
+			}
+
+			return true
+		})
+
+		return true
+	})
+
+	return nil
+}
+
+// parseOcpLatencyTimestamp returns the seconds since the Unix epoch of a latency monitor timestamp.
+// Depending on the version, nvme-cli prints the timestamps as milliseconds since the epoch
+// or as formatted dates, and "NA" for the buckets without a measured latency.
+func parseOcpLatencyTimestamp(value gjson.Result) (float64, bool) {
+	if value.Type == gjson.Number {
+		// All bits set means that no latency was measured
+		if value.Uint() == math.MaxUint64 || value.Int() == -1 {
+			return 0, false
+		}
+
+		return value.Float() / 1000, true
+	}
+
+	timestamp, err := time.Parse(ocpLatencyTimestampLayout, value.String())
+	if err != nil {
+		return 0, false
+	}
+
+	return float64(timestamp.UnixMilli()) / 1000, true
+}
@@ -0,0 +1,64 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+func TestOcpLatencyMetricCollector(t *testing.T) {
+	t.Parallel()
+
+	// Two controllers reaching the same namespace read their own log once each
+	subsystem := newMultipathTestSubsystem()
+
+	reads := make(map[string]int)
+
+	getData := func(devicePath string) gjson.Result {
+		reads[devicePath]++
+
+		return gjson.Parse(`{
+			"Active Threshold A": 5,
+			"Active Bucket Counter: Bucket 0": {"Read": 12, "Write": 3, "Trim": 0},
+			"Static Measured Latency: Bucket 1": {"Read": 25, "Write": 40, "Deallocate": 1},
+			"Active Latency Time Stamp: Bucket 0": {"Read": 1700000000123, "Write": "NA", "Trim": -1},
+			"Static Latency Time Stamp: Bucket 1": {"Read": "2023-11-14T22:13:20.500 GMT"}
+		}`)
+	}
+
+	collector := newTestCollector("ocp_latency", pkg.NewOcpLatencyMetricCollector(getData), subsystem)
+
+	values := gatherValues(t, collector)
+	timestamp := "nvme_ocp_latency_max_timestamp_seconds"
+
+	checkValues(t, values, map[string]float64{
+		`nvme_ocp_latency_threshold_seconds{controller="nvme1",threshold="A"}`:                                   0.005,
+		`nvme_ocp_latency_bucket_commands{bucket="0",controller="nvme1",operation="read",window="active"}`:       12,
+		`nvme_ocp_latency_bucket_commands{bucket="0",controller="nvme2",operation="deallocate",window="active"}`: 0,
+		`nvme_ocp_latency_max_seconds{bucket="1",controller="nvme2",operation="write",window="static"}`:          0.04,
+
+		// The timestamps are printed as milliseconds or as dates depending on the nvme-cli version
+		timestamp + `{bucket="0",controller="nvme1",operation="read",window="active"}`: 1700000000.123,
+		timestamp + `{bucket="1",controller="nvme1",operation="read",window="static"}`: 1700000000.5,
+	})
+
+	// The buckets without a measured latency have no timestamp
+	for _, operation := range []string{"write", "deallocate"} {
+		key := timestamp + `{bucket="0",controller="nvme1",operation="` + operation + `",window="active"}`
+		if _, found := values[key]; found {
+			t.Errorf("%s is exported", key)
+		}
+	}
+
+	for devicePath, count := range reads {
+		if count != 1 {
+			t.Errorf("the log of %s was read %d times, want 1", devicePath, count)
+		}
+	}
+
+	if len(reads) != 2 {
+		t.Errorf("the log was read on %d devices, want 2 controllers", len(reads))
+	}
+}
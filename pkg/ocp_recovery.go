@@ -0,0 +1,151 @@
+package pkg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// ocpPanicIDKey is the JSON key of the panic identifier in the OCP error recovery log.
+const ocpPanicIDKey = "Panic ID"
+
+// OcpErrorRecoveryMetricCollector implements ControllerMetricCollector and sends the metrics
+// of the OCP Error Recovery log page (C1h) of each controller, together with the number
+// of panic identifier changes observed across collections.
+type OcpErrorRecoveryMetricCollector struct {
+	// LogMetricProviders is the list of providers for the error recovery log fields
+	LogMetricProviders []MetricProvider
+
+	// getData receives the controller device path and gets the error recovery log JSON data
+	getData func(string) gjson.Result
+
+	panicInfoDesc      *prometheus.Desc
+	panicIDChangesDesc *prometheus.Desc
+
+	// mutex protects the fields below, since the registry may collect concurrently
+	mutex sync.Mutex
+	// lastPanicIDs holds the panic identifier of each controller at the previous collection
+	lastPanicIDs map[string]string
+	// panicIDChanges holds the number of panic identifier changes observed for each controller
+	panicIDChanges map[string]float64
+}
+
+// NewOcpErrorRecoveryMetricCollector initializes and returns a new OcpErrorRecoveryMetricCollector object.
+func NewOcpErrorRecoveryMetricCollector(
+	providers []MetricProvider,
+	getData func(string) gjson.Result,
+) *OcpErrorRecoveryMetricCollector {
+	return &OcpErrorRecoveryMetricCollector{
+		LogMetricProviders: providers,
+		getData:            getData,
+		panicInfoDesc: prometheus.NewDesc(
+			"nvme_ocp_panic_info",
+			"Identifier of the last panic of the controller reported by the OCP error recovery log, "+
+				"all zeros if none, value is always 1",
+			[]string{"controller", "panic_id"},
+			nil,
+		),
+		panicIDChangesDesc: prometheus.NewDesc(
+			"nvme_ocp_panic_id_changes_total",
+			"Number of times the panic ID of the OCP error recovery log was observed changing "+
+				"since the exporter started",
+			[]string{"controller"},
+			nil,
+		),
+		lastPanicIDs:   make(map[string]string),
+		panicIDChanges: make(map[string]float64),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (oc *OcpErrorRecoveryMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, logProvider := range oc.LogMetricProviders {
+		ch <- logProvider.Desc
+	}
+
+	ch <- oc.panicInfoDesc
+	ch <- oc.panicIDChangesDesc
+}
+
+// CollectControllerMetrics gets the error recovery log data of the controller
+// and sends all its metrics through the channel.
+func (oc *OcpErrorRecoveryMetricCollector) CollectControllerMetrics(
+	ch chan<- prometheus.Metric,
+	controller *Controller,
+) error {
+	if controller.Name == "" {
+		return nil
+	}
+
+	devicePath := "/dev/" + controller.Name
+
+	jsonData := oc.getData(devicePath)
+
+	// If getData returns invalid data (e.g., error recovery log not supported), skip this collector
+	if !jsonData.Exists() {
+		return noDataError(devicePath, jsonData)
+	}
+
+	for _, logProvider := range oc.LogMetricProviders {
+		metric := logProvider.GetMetric(jsonData, controller.Name)
+		if metric != nil {
+			ch <- metric
+		}
+	}
+
+	panicID := jsonData.Get(ocpPanicIDKey)
+	if !panicID.Exists() {
+		return nil
+	}
+
+	// The 64-bit panic identifier cannot be represented exactly as a metric value
+	ch <- prometheus.MustNewConstMetric(
+		oc.panicInfoDesc,
+		prometheus.GaugeValue,
+		1,
+		controller.Name,
+		ocpPanicIDLabel(panicID),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		oc.panicIDChangesDesc,
+		prometheus.CounterValue,
+		oc.trackPanicIDChanges(controller.Name, panicID.String()),
+		controller.Name,
+	)
+
+	return nil
+}
+
+// trackPanicIDChanges updates the panic identifier changes counter of the controller,
+// incrementing it when the panic identifier differs from the previous collection, and returns it.
+// Several panics between two collections are counted once.
+func (oc *OcpErrorRecoveryMetricCollector) trackPanicIDChanges(controller string, panicID string) float64 {
+	oc.mutex.Lock()
+	defer oc.mutex.Unlock()
+
+	lastPanicID, found := oc.lastPanicIDs[controller]
+	if found && lastPanicID != panicID {
+		oc.panicIDChanges[controller]++
+	}
+
+	oc.lastPanicIDs[controller] = panicID
+
+	return oc.panicIDChanges[controller]
+}
+
+// ocpPanicIDLabel returns the panic identifier as a 16 digit hex label value.
+// Depending on the version, nvme-cli prints the panic identifier as a number or a hex string.
+func ocpPanicIDLabel(panicID gjson.Result) string {
+	// The string of an integer is its raw JSON text, without the precision loss of its float64 value
+	value, err := strconv.ParseUint(panicID.String(), 0, 64)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(panicID.String()))
+	}
+
+	return fmt.Sprintf("%016x", value)
+}
@@ -0,0 +1,69 @@
+package pkg_test
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+func TestOcpErrorRecoveryMetricCollector(t *testing.T) {
+	t.Parallel()
+
+	subsystem := &pkg.Subsystem{Name: "nvme-subsys0"}
+	subsystem.Controllers = []*pkg.Controller{
+		{Name: "nvme0", Subsystem: subsystem},
+		{Name: "nvme1", Subsystem: subsystem},
+	}
+
+	providers := []pkg.MetricProvider{
+		pkg.NewMetricProvider(
+			prometheus.NewDesc("nvme_ocp_panic_reset_action", "Test value", []string{"controller"}, nil),
+			prometheus.GaugeValue,
+			"Panic Reset Action",
+		),
+	}
+
+	var panicID atomic.Value
+
+	panicID.Store(`"0x0"`)
+
+	getData := func(devicePath string) gjson.Result {
+		if devicePath == "/dev/nvme1" {
+			// A 64-bit panic identifier printed as a number, beyond the float64 precision
+			return gjson.Parse(`{"Panic Reset Action": 2, "Panic ID": 18364758544493064720}`)
+		}
+
+		return gjson.Parse(`{"Panic Reset Action": 1, "Panic ID": ` + panicID.Load().(string) + `}`)
+	}
+
+	collector := newTestCollector("ocp_recovery", pkg.NewOcpErrorRecoveryMetricCollector(providers, getData),
+		subsystem)
+
+	checkValues(t, gatherValues(t, collector), map[string]float64{
+		`nvme_ocp_panic_reset_action{controller="nvme0"}`:                               1,
+		`nvme_ocp_panic_reset_action{controller="nvme1"}`:                               2,
+		`nvme_ocp_panic_info{controller="nvme0",panic_id="0000000000000000"}`:           1,
+		`nvme_ocp_panic_info{controller="nvme1",panic_id="fedcba9876543210"}`:           1,
+		`nvme_ocp_panic_id_changes_total{controller="nvme0"}`:                           0,
+		`nvme_exporter_collector_success{collector="ocp_recovery",device="/dev/nvme0"}`: 1,
+	})
+
+	// A new panic changes the identifier label and is counted
+	panicID.Store(`"0xDEADBEEF00000001"`)
+
+	values := gatherValues(t, collector)
+
+	checkValues(t, values, map[string]float64{
+		`nvme_ocp_panic_info{controller="nvme0",panic_id="deadbeef00000001"}`: 1,
+		`nvme_ocp_panic_id_changes_total{controller="nvme0"}`:                 1,
+		`nvme_ocp_panic_id_changes_total{controller="nvme1"}`:                 0,
+	})
+
+	if _, found := values[`nvme_ocp_panic_info{controller="nvme0",panic_id="0000000000000000"}`]; found {
+		t.Error("the previous panic identifier is still exported")
+	}
+}
@@ -0,0 +1,236 @@
+package pkg
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// persistentEventTimestampMask selects the milliseconds since the Unix epoch
+// in the event timestamps, the upper bits describe the timestamp origin.
+const persistentEventTimestampMask = 1<<48 - 1
+
+// persistentEventTypes maps the event types printed by nvme persistent-event-log
+// to the event label values.
+var persistentEventTypes = map[string]string{
+	"SMART / Health Log Snapshot Event":  "smart_snapshot",
+	"Firmware Commit Event":              "firmware_commit",
+	"Timestamp Change Event":             "timestamp_change",
+	"Power-on or Reset Event":            "power_on_reset",
+	"NVM Subsystem Hardware Error Event": "nss_hardware_error",
+	"Change Namespace Event":             "change_namespace",
+	"Format NVM Start Event":             "format_start",
+	"Format NVM Completion Event":        "format_completion",
+	"Sanitize Start Event":               "sanitize_start",
+	"Sanitize Completion Event":          "sanitize_completion",
+	"Set Feature Event":                  "set_feature",
+	"Telemetry Log Create Event":         "telemetry_log_create",
+	"Thermal Excursion Event":            "thermal_excursion",
+	"Vendor Specific Event":              "vendor_specific",
+	"TCG Defined Event":                  "tcg_defined",
+}
+
+// persistentEventCursor identifies the last event counted for a controller.
+type persistentEventCursor struct {
+	timestamp    uint64
+	eventType    string
+	controllerID int64
+}
+
+// PersistentEventMetricCollector implements ControllerMetricCollector and sends
+// the number of events of each type recorded in the Persistent Event log (0Dh)
+// and the timestamp of the latest one.
+//
+// The log is a ring buffer, so the events are counted once and accumulated across
+// collections: a cursor on the last counted event prevents re-counting the events
+// already in the log at the previous collection. Since the whole log is read each time,
+// it is read at most once per refresh interval, and the counters are sent as they are
+// in between.
+type PersistentEventMetricCollector struct {
+	// getData receives the controller device path and gets the persistent event log JSON data
+	getData func(string) gjson.Result
+	// refreshInterval is the minimum delay between two reads of the log of a controller
+	refreshInterval time.Duration
+
+	eventsDesc    *prometheus.Desc
+	timestampDesc *prometheus.Desc
+
+	// mutex protects the fields below, since the registry may collect concurrently
+	mutex sync.Mutex
+	// readTimes holds the time of the last read of the log of each controller
+	readTimes map[string]time.Time
+	// cursors holds the last event counted for each controller
+	cursors map[string]persistentEventCursor
+	// events holds the number of events counted for each controller and event type
+	events map[string]map[string]float64
+	// timestamps holds the timestamp of the latest event for each controller and event type
+	timestamps map[string]map[string]float64
+}
+
+// NewPersistentEventMetricCollector initializes and returns a new PersistentEventMetricCollector object.
+func NewPersistentEventMetricCollector(
+	getData func(string) gjson.Result,
+	refreshInterval time.Duration,
+) *PersistentEventMetricCollector {
+	labels := []string{"controller", "event"}
+
+	return &PersistentEventMetricCollector{
+		getData:         getData,
+		refreshInterval: refreshInterval,
+		eventsDesc: prometheus.NewDesc(
+			"nvme_persistent_events_total",
+			"Number of events recorded in the persistent event log by event type",
+			labels,
+			nil,
+		),
+		timestampDesc: prometheus.NewDesc(
+			"nvme_persistent_event_last_timestamp_seconds",
+			"Timestamp of the latest event recorded in the persistent event log by event type, "+
+				"in seconds since the Unix epoch",
+			labels,
+			nil,
+		),
+		readTimes:  make(map[string]time.Time),
+		cursors:    make(map[string]persistentEventCursor),
+		events:     make(map[string]map[string]float64),
+		timestamps: make(map[string]map[string]float64),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (pc *PersistentEventMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pc.eventsDesc
+	ch <- pc.timestampDesc
+}
+
+// CollectControllerMetrics gets the persistent event log of the controller if its refresh
+// interval elapsed, counts the events added since the previous read and sends the metrics
+// through the channel.
+func (pc *PersistentEventMetricCollector) CollectControllerMetrics(
+	ch chan<- prometheus.Metric,
+	controller *Controller,
+) error {
+	if controller.Name == "" {
+		return nil
+	}
+
+	events, timestamps, found := pc.cachedEvents(controller.Name)
+	if !found {
+		jsonData := pc.getData("/dev/" + controller.Name)
+		if !jsonData.Exists() {
+			return noDataError("/dev/"+controller.Name, jsonData)
+		}
+
+		events, timestamps = pc.trackEvents(controller.Name, jsonData.Get("list_of_event_entries").Array())
+	}
+
+	for event, count := range events {
+		ch <- prometheus.MustNewConstMetric(pc.eventsDesc, prometheus.CounterValue, count, controller.Name, event)
+	}
+
+	for event, timestamp := range timestamps {
+		ch <- prometheus.MustNewConstMetric(pc.timestampDesc, prometheus.GaugeValue, timestamp, controller.Name, event)
+	}
+
+	return nil
+}
+
+// cachedEvents returns copies of the event counters and timestamps of the controller
+// if its log was read within the refresh interval.
+func (pc *PersistentEventMetricCollector) cachedEvents(
+	controller string,
+) (map[string]float64, map[string]float64, bool) {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	readTime, found := pc.readTimes[controller]
+	if !found || time.Since(readTime) >= pc.refreshInterval {
+		return nil, nil, false
+	}
+
+	events, timestamps := pc.copyEvents(controller)
+
+	return events, timestamps, true
+}
+
+// trackEvents counts the entries following the cursor of the controller, moves the cursor
+// to the last entry and returns copies of the event counters and timestamps.
+// If the cursor entry is not found (e.g. the first collection, or the log wrapped around
+// since the previous collection) all the entries are counted.
+func (pc *PersistentEventMetricCollector) trackEvents(
+	controller string,
+	entries []gjson.Result,
+) (map[string]float64, map[string]float64) {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	if pc.events[controller] == nil {
+		pc.events[controller] = make(map[string]float64)
+		pc.timestamps[controller] = make(map[string]float64)
+
+		for _, event := range persistentEventTypes {
+			pc.events[controller][event] = 0
+		}
+	}
+
+	start := 0
+
+	if cursor, found := pc.cursors[controller]; found {
+		for i := len(entries) - 1; i >= 0; i-- {
+			if newPersistentEventCursor(entries[i]) == cursor {
+				start = i + 1
+
+				break
+			}
+		}
+	}
+
+	for _, entry := range entries[start:] {
+		event, found := persistentEventTypes[entry.Get("event_type").String()]
+		if !found {
+			event = toSnakeCase(entry.Get("event_type").String())
+		}
+
+		pc.events[controller][event]++
+
+		timestamp := entry.Get("event_time_stamp").Uint() & persistentEventTimestampMask
+		if timestamp > 0 {
+			pc.timestamps[controller][event] = float64(timestamp) / 1000
+		}
+	}
+
+	if len(entries) > 0 {
+		pc.cursors[controller] = newPersistentEventCursor(entries[len(entries)-1])
+	}
+
+	pc.readTimes[controller] = time.Now()
+
+	return pc.copyEvents(controller)
+}
+
+// copyEvents returns copies of the event counters and timestamps of the controller.
+// The mutex must be held.
+func (pc *PersistentEventMetricCollector) copyEvents(controller string) (map[string]float64, map[string]float64) {
+	events := make(map[string]float64, len(pc.events[controller]))
+	for event, count := range pc.events[controller] {
+		events[event] = count
+	}
+
+	timestamps := make(map[string]float64, len(pc.timestamps[controller]))
+	for event, timestamp := range pc.timestamps[controller] {
+		timestamps[event] = timestamp
+	}
+
+	return events, timestamps
+}
+
+// newPersistentEventCursor returns the cursor identifying a persistent event log entry.
+func newPersistentEventCursor(entry gjson.Result) persistentEventCursor {
+	return persistentEventCursor{
+		timestamp:    entry.Get("event_time_stamp").Uint(),
+		eventType:    entry.Get("event_type").String(),
+		controllerID: entry.Get("ctrl_id").Int(),
+	}
+}
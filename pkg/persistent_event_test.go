@@ -0,0 +1,137 @@
+package pkg_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+// persistentEventLog is a fake persistent event log whose entries can be changed between collections.
+type persistentEventLog struct {
+	mutex   sync.Mutex
+	entries []string
+	reads   int
+}
+
+// set replaces the entries of the log.
+func (pl *persistentEventLog) set(entries ...string) {
+	pl.mutex.Lock()
+	defer pl.mutex.Unlock()
+
+	pl.entries = entries
+}
+
+// getData returns the log JSON data, as printed by nvme persistent-event-log.
+func (pl *persistentEventLog) getData(string) gjson.Result {
+	pl.mutex.Lock()
+	defer pl.mutex.Unlock()
+
+	pl.reads++
+
+	return gjson.Parse(`{"list_of_event_entries": [` + strings.Join(pl.entries, ",") + `]}`)
+}
+
+// persistentEvent returns a log entry. The timestamp is in milliseconds, with
+// the timestamp origin in the upper bits.
+func persistentEvent(eventType string, seconds int) string {
+	return fmt.Sprintf(`{"event_type": %q, "event_time_stamp": %d, "ctrl_id": 1}`,
+		eventType, uint64(seconds)*1000|1<<49)
+}
+
+// newPersistentEventTestCollector returns a composite collector running the persistent event
+// collector on the controller nvme0.
+func newPersistentEventTestCollector(
+	eventLog *persistentEventLog,
+	refreshInterval time.Duration,
+) *pkg.CompositeCollector {
+	subsystem := &pkg.Subsystem{Name: "nvme-subsys0"}
+	subsystem.Controllers = []*pkg.Controller{{Name: "nvme0", Subsystem: subsystem}}
+
+	return newTestCollector("persistent-event",
+		pkg.NewPersistentEventMetricCollector(eventLog.getData, refreshInterval), subsystem)
+}
+
+// checkPersistentEvents checks the power on and firmware commit event counters of nvme0.
+func checkPersistentEvents(t *testing.T, collector *pkg.CompositeCollector, powerOn float64, firmwareCommit float64) {
+	t.Helper()
+
+	checkValues(t, gatherValues(t, collector), map[string]float64{
+		`nvme_persistent_events_total{controller="nvme0",event="power_on_reset"}`:  powerOn,
+		`nvme_persistent_events_total{controller="nvme0",event="firmware_commit"}`: firmwareCommit,
+		// The event types never seen are exported as zero
+		`nvme_persistent_events_total{controller="nvme0",event="sanitize_start"}`: 0,
+	})
+}
+
+func TestPersistentEventMetricCollector(t *testing.T) {
+	t.Parallel()
+
+	powerOn1 := persistentEvent("Power-on or Reset Event", 1700000000)
+	commit := persistentEvent("Firmware Commit Event", 1700000100)
+	powerOn2 := persistentEvent("Power-on or Reset Event", 1700000200)
+	powerOn3 := persistentEvent("Power-on or Reset Event", 1700000300)
+	powerOn4 := persistentEvent("Power-on or Reset Event", 1700000400)
+
+	eventLog := &persistentEventLog{}
+	eventLog.set(powerOn1, commit)
+
+	collector := newPersistentEventTestCollector(eventLog, 0)
+
+	// The first collection counts all the events in the log
+	checkPersistentEvents(t, collector, 1, 1)
+
+	values := gatherValues(t, collector)
+	checkValues(t, values, map[string]float64{
+		`nvme_persistent_event_last_timestamp_seconds{controller="nvme0",event="power_on_reset"}`:  1700000000,
+		`nvme_persistent_event_last_timestamp_seconds{controller="nvme0",event="firmware_commit"}`: 1700000100,
+	})
+
+	// The events already counted are not counted again
+	checkPersistentEvents(t, collector, 1, 1)
+
+	eventLog.set(powerOn1, commit, powerOn2)
+	checkPersistentEvents(t, collector, 2, 1)
+
+	// The log wrapped around and the last counted event was overwritten: all the events are new
+	eventLog.set(powerOn3, powerOn4)
+	checkPersistentEvents(t, collector, 4, 1)
+
+	// The log was cleared, then a new event was recorded
+	eventLog.set()
+	checkPersistentEvents(t, collector, 4, 1)
+
+	eventLog.set(powerOn1)
+	checkPersistentEvents(t, collector, 5, 1)
+
+	// A restarted exporter counts all the events in the log again, as a counter reset
+	eventLog.set(powerOn1, commit, powerOn2)
+	checkPersistentEvents(t, newPersistentEventTestCollector(eventLog, 0), 2, 1)
+}
+
+func TestPersistentEventRefreshInterval(t *testing.T) {
+	t.Parallel()
+
+	eventLog := &persistentEventLog{}
+	eventLog.set(persistentEvent("Power-on or Reset Event", 1700000000))
+
+	collector := newPersistentEventTestCollector(eventLog, time.Hour)
+
+	checkPersistentEvents(t, collector, 1, 0)
+
+	// The log is not read again within the refresh interval, the last counters are exported
+	eventLog.set(
+		persistentEvent("Power-on or Reset Event", 1700000000),
+		persistentEvent("Power-on or Reset Event", 1700000100),
+	)
+	checkPersistentEvents(t, collector, 1, 0)
+
+	if eventLog.reads != 1 {
+		t.Errorf("the log was read %d times, want 1", eventLog.reads)
+	}
+}
@@ -0,0 +1,228 @@
+package pkg
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// Feature identifiers read by the power collector.
+const (
+	featurePowerManagement = 0x02
+	featureAPST            = 0x0c
+)
+
+const (
+	// powerStateMask selects the Power State field of the Power Management feature.
+	powerStateMask = 0x1f
+	// apstEnableBit is the Autonomous Power State Transition Enable bit of the APST feature.
+	apstEnableBit = 1
+	// powerScaleBit is the Max Power Scale bit of the power state descriptor flags.
+	powerScaleBit = 1
+	// powerScaleWatts and powerScaleSmallWatts are the units of Max Power, in watts,
+	// depending on the Max Power Scale flag.
+	powerScaleWatts      = 0.01
+	powerScaleSmallWatts = 0.0001
+	// powerLatencyUnit is the unit of the entry and exit latencies, in seconds.
+	powerLatencyUnit = 0.000001
+)
+
+// PowerMetricCollector implements ControllerMetricCollector and sends the power state
+// descriptors of Identify Controller, the current power state of the Power Management
+// feature and the Autonomous Power State Transition configuration. The current power
+// draw is estimated as the maximum power of the current power state.
+type PowerMetricCollector struct {
+	// getIdentifyData receives the controller device path and gets the Identify Controller JSON data
+	getIdentifyData func(string) gjson.Result
+	// getFeatureData receives the controller device path, the feature identifier
+	// and the command dword 11 and gets the current feature value JSON data
+	getFeatureData func(string, int, uint32) gjson.Result
+
+	maxPowerDesc       *prometheus.Desc
+	entryLatencyDesc   *prometheus.Desc
+	exitLatencyDesc    *prometheus.Desc
+	nonOperationalDesc *prometheus.Desc
+	currentStateDesc   *prometheus.Desc
+	estimatedPowerDesc *prometheus.Desc
+	apstSupportedDesc  *prometheus.Desc
+	apstEnabledDesc    *prometheus.Desc
+}
+
+// NewPowerMetricCollector initializes and returns a new PowerMetricCollector object.
+func NewPowerMetricCollector(
+	getIdentifyData func(string) gjson.Result,
+	getFeatureData func(string, int, uint32) gjson.Result,
+) *PowerMetricCollector {
+	stateLabels := []string{"controller", "power_state"}
+
+	return &PowerMetricCollector{
+		getIdentifyData: getIdentifyData,
+		getFeatureData:  getFeatureData,
+		maxPowerDesc: prometheus.NewDesc(
+			"nvme_power_state_max_power_watts",
+			"Maximum power consumed by the controller in the power state",
+			stateLabels,
+			nil,
+		),
+		entryLatencyDesc: prometheus.NewDesc(
+			"nvme_power_state_entry_latency_seconds",
+			"Maximum latency to enter the power state",
+			stateLabels,
+			nil,
+		),
+		exitLatencyDesc: prometheus.NewDesc(
+			"nvme_power_state_exit_latency_seconds",
+			"Maximum latency to exit the power state",
+			stateLabels,
+			nil,
+		),
+		nonOperationalDesc: prometheus.NewDesc(
+			"nvme_power_state_non_operational",
+			"Whether the controller processes no I/O commands in the power state (1 = non-operational)",
+			stateLabels,
+			nil,
+		),
+		currentStateDesc: prometheus.NewDesc(
+			"nvme_power_state_current",
+			"Current power state of the controller",
+			[]string{"controller"},
+			nil,
+		),
+		estimatedPowerDesc: prometheus.NewDesc(
+			"nvme_power_estimated_watts",
+			"Estimated power draw of the controller, as the maximum power of the current power state",
+			[]string{"controller"},
+			nil,
+		),
+		apstSupportedDesc: prometheus.NewDesc(
+			"nvme_apst_supported",
+			"Whether the controller supports autonomous power state transitions (1 = supported)",
+			[]string{"controller"},
+			nil,
+		),
+		apstEnabledDesc: prometheus.NewDesc(
+			"nvme_apst_enabled",
+			"Whether autonomous power state transitions are enabled (1 = enabled)",
+			[]string{"controller"},
+			nil,
+		),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (pc *PowerMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pc.maxPowerDesc
+	ch <- pc.entryLatencyDesc
+	ch <- pc.exitLatencyDesc
+	ch <- pc.nonOperationalDesc
+	ch <- pc.currentStateDesc
+	ch <- pc.estimatedPowerDesc
+	ch <- pc.apstSupportedDesc
+	ch <- pc.apstEnabledDesc
+}
+
+// CollectControllerMetrics gets the power state descriptors and power features
+// of the controller and sends the power metrics through the channel.
+func (pc *PowerMetricCollector) CollectControllerMetrics(ch chan<- prometheus.Metric, controller *Controller) error {
+	if controller.Name == "" {
+		return nil
+	}
+
+	devicePath := "/dev/" + controller.Name
+
+	identifyData := pc.getIdentifyData(devicePath)
+	if !identifyData.Exists() {
+		return noDataError(devicePath, identifyData)
+	}
+
+	maxPowers := make(map[uint64]float64)
+
+	for index, descriptor := range identifyData.Get("psds").Array() {
+		powerState := strconv.Itoa(index)
+
+		maxPowers[uint64(index)] = powerStateMaxPower(descriptor)
+
+		ch <- prometheus.MustNewConstMetric(
+			pc.maxPowerDesc, prometheus.GaugeValue, maxPowers[uint64(index)], controller.Name, powerState,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			pc.entryLatencyDesc,
+			prometheus.GaugeValue,
+			descriptor.Get("entry_lat").Float()*powerLatencyUnit,
+			controller.Name,
+			powerState,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			pc.exitLatencyDesc,
+			prometheus.GaugeValue,
+			descriptor.Get("exit_lat").Float()*powerLatencyUnit,
+			controller.Name,
+			powerState,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			pc.nonOperationalDesc,
+			prometheus.GaugeValue,
+			descriptor.Get("non-operational_state").Float(),
+			controller.Name,
+			powerState,
+		)
+	}
+
+	if apsta := identifyData.Get("apsta"); apsta.Exists() {
+		ch <- prometheus.MustNewConstMetric(
+			pc.apstSupportedDesc, prometheus.GaugeValue, float64(apsta.Uint()&1), controller.Name,
+		)
+	}
+
+	if value, found := featureCurrentValue(pc.getFeatureData(devicePath, featurePowerManagement, 0)); found {
+		powerState := value & powerStateMask
+
+		ch <- prometheus.MustNewConstMetric(
+			pc.currentStateDesc, prometheus.GaugeValue, float64(powerState), controller.Name,
+		)
+
+		if maxPower, found := maxPowers[powerState]; found {
+			ch <- prometheus.MustNewConstMetric(
+				pc.estimatedPowerDesc, prometheus.GaugeValue, maxPower, controller.Name,
+			)
+		}
+	}
+
+	if value, found := featureCurrentValue(pc.getFeatureData(devicePath, featureAPST, 0)); found {
+		ch <- prometheus.MustNewConstMetric(
+			pc.apstEnabledDesc, prometheus.GaugeValue, float64(value&apstEnableBit), controller.Name,
+		)
+	}
+
+	return nil
+}
+
+// powerStateMaxPower returns the maximum power of a power state descriptor in watts.
+func powerStateMaxPower(descriptor gjson.Result) float64 {
+	scale := powerScaleWatts
+	if descriptor.Get("max_power_scale").Uint()&powerScaleBit != 0 {
+		scale = powerScaleSmallWatts
+	}
+
+	return descriptor.Get("max_power").Float() * scale
+}
+
+// featureCurrentValue returns the current value of a feature, printed by nvme get-feature as a hex string.
+func featureCurrentValue(featureData gjson.Result) (uint64, bool) {
+	current := featureData.Get("Current")
+	if !current.Exists() {
+		return 0, false
+	}
+
+	if current.Type == gjson.Number {
+		return current.Uint(), true
+	}
+
+	value, err := strconv.ParseUint(current.String(), 0, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return value, true
+}
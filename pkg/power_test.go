@@ -0,0 +1,52 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+func TestPowerMetricCollector(t *testing.T) {
+	t.Parallel()
+
+	subsystem := &pkg.Subsystem{Name: "nvme-subsys0"}
+	subsystem.Controllers = []*pkg.Controller{{Name: "nvme0", Subsystem: subsystem}}
+
+	getIdentifyData := func(string) gjson.Result {
+		// Power state 2 reports its maximum power in units of 0.0001 W
+		return gjson.Parse(`{"apsta": 1, "psds": [
+			{"max_power": 2500, "max_power_scale": 0, "non-operational_state": 0, "entry_lat": 0, "exit_lat": 0},
+			{"max_power": 1200, "max_power_scale": 0, "non-operational_state": 0, "entry_lat": 10, "exit_lat": 20},
+			{"max_power": 50, "max_power_scale": 1, "non-operational_state": 1, "entry_lat": 5000, "exit_lat": 10000}
+		]}`)
+	}
+
+	getFeatureData := func(_ string, featureID int, _ uint32) gjson.Result {
+		switch featureID {
+		case 0x02:
+			// Power state 1, with the workload hint in the upper bits
+			return gjson.Parse(`{"Current": "0x00000021"}`)
+		case 0x0c:
+			return gjson.Parse(`{"Current": "0x00000001"}`)
+		default:
+			return gjson.Result{}
+		}
+	}
+
+	collector := newTestCollector("power", pkg.NewPowerMetricCollector(getIdentifyData, getFeatureData), subsystem)
+
+	checkValues(t, gatherValues(t, collector), map[string]float64{
+		`nvme_power_state_max_power_watts{controller="nvme0",power_state="0"}`:       25,
+		`nvme_power_state_max_power_watts{controller="nvme0",power_state="2"}`:       0.005,
+		`nvme_power_state_entry_latency_seconds{controller="nvme0",power_state="2"}`: 0.005,
+		`nvme_power_state_exit_latency_seconds{controller="nvme0",power_state="2"}`:  0.01,
+		`nvme_power_state_non_operational{controller="nvme0",power_state="2"}`:       1,
+		`nvme_power_state_current{controller="nvme0"}`:                               1,
+		// The estimated power is the maximum power of the current power state
+		`nvme_power_estimated_watts{controller="nvme0"}`: 12,
+		`nvme_apst_supported{controller="nvme0"}`:        1,
+		`nvme_apst_enabled{controller="nvme0"}`:          1,
+	})
+}
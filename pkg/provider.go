@@ -17,6 +17,10 @@ type MetricProvider struct {
 	// jsonKey is the string key that the object needs to access
 	// in the device JSON to fetch the metric float64 value
 	jsonKey string
+
+	// optional marks the fields that not every data source reports (e.g. fields added by
+	// recent nvme-cli versions), which are skipped when missing instead of exported as zero
+	optional bool
 }
 
 // NewMetricProvider is the constructor for MetricProvider objects.
@@ -33,6 +37,19 @@ func NewMetricProvider(
 	}
 }
 
+// NewOptionalMetricProvider is the constructor for the MetricProvider objects
+// of the optional fields, which are skipped when missing from the device JSON.
+func NewOptionalMetricProvider(
+	desc *prometheus.Desc,
+	valueType prometheus.ValueType,
+	jsonKey string,
+) MetricProvider {
+	provider := NewMetricProvider(desc, valueType, jsonKey)
+	provider.optional = true
+
+	return provider
+}
+
 // GetMetric computes the metric from the
 // data in JSON form.
 func (ip MetricProvider) GetMetric(
@@ -46,6 +63,11 @@ func (ip MetricProvider) GetMetric(
 
 	result := data.Get(ip.jsonKey)
 
+	// Skip the optional fields the data source does not report instead of exporting them as zero
+	if ip.optional && !result.Exists() {
+		return nil
+	}
+
 	// Handle both scalar values (v2.8) and object values (v2.11+)
 	// In v2.11+, some fields like critical_warning are objects with a "value" field
 	var value float64
@@ -66,3 +88,93 @@ func (ip MetricProvider) GetMetric(
 
 	return metric
 }
+
+// NamespaceMetricProvider is an object that computes the info metric
+// from the typed namespace data.
+type NamespaceMetricProvider struct {
+	// Desc holds the pointer to the prometheus.desc object
+	Desc *prometheus.Desc
+
+	// ValueType holds the prometheus.ValueType
+	ValueType prometheus.ValueType
+
+	// value extracts the metric float64 value from the namespace,
+	// returning false if the value is not available
+	value func(*Namespace) (float64, bool)
+}
+
+// NewNamespaceMetricProvider is the constructor for NamespaceMetricProvider objects.
+func NewNamespaceMetricProvider(
+	desc *prometheus.Desc,
+	valueType prometheus.ValueType,
+	value func(*Namespace) (float64, bool),
+) NamespaceMetricProvider {
+	return NamespaceMetricProvider{
+		Desc:      desc,
+		ValueType: valueType,
+		value:     value,
+	}
+}
+
+// GetMetric computes the metric from the namespace data.
+func (np NamespaceMetricProvider) GetMetric(
+	namespace *Namespace,
+	labels ...string,
+) prometheus.Metric {
+	value, ok := np.value(namespace)
+	if !ok {
+		return nil
+	}
+
+	return prometheus.MustNewConstMetric(
+		np.Desc,
+		np.ValueType,
+		value,
+		labels...,
+	)
+}
+
+// LogInfoMetricProvider is an object that computes an info metric,
+// whose value is always 1, from the string fields of the device data in JSON format.
+type LogInfoMetricProvider struct {
+	// Desc holds the pointer to the prometheus.desc object
+	Desc *prometheus.Desc
+
+	// labelValues extracts the info label values from the device JSON,
+	// returning false if the fields are not available
+	labelValues func(gjson.Result) ([]string, bool)
+}
+
+// NewLogInfoMetricProvider is the constructor for LogInfoMetricProvider objects.
+func NewLogInfoMetricProvider(
+	desc *prometheus.Desc,
+	labelValues func(gjson.Result) ([]string, bool),
+) LogInfoMetricProvider {
+	return LogInfoMetricProvider{
+		Desc:        desc,
+		labelValues: labelValues,
+	}
+}
+
+// GetMetric computes the info metric from the data in JSON form.
+// The info label values follow the given labels.
+func (lp LogInfoMetricProvider) GetMetric(
+	data gjson.Result,
+	labels ...string,
+) prometheus.Metric {
+	if !data.Exists() {
+		return nil
+	}
+
+	infoLabels, ok := lp.labelValues(data)
+	if !ok {
+		return nil
+	}
+
+	return prometheus.MustNewConstMetric(
+		lp.Desc,
+		prometheus.GaugeValue,
+		1,
+		append(labels, infoLabels...)...,
+	)
+}
@@ -0,0 +1,172 @@
+package pkg
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// namespaceSharedBit is the bit of the NMIC field of Identify Namespace
+	// reporting whether the namespace may be attached to several controllers.
+	namespaceSharedBit = 1
+	// reservationHolderBit is the bit of the Reservation Status field of a registrant
+	// reporting whether it holds the reservation.
+	reservationHolderBit = 1
+)
+
+// reservationTypes are the type label values of the Reservation Type codes.
+var reservationTypes = []string{
+	"none",
+	"write_exclusive",
+	"exclusive_access",
+	"write_exclusive_registrants_only",
+	"exclusive_access_registrants_only",
+	"write_exclusive_all_registrants",
+	"exclusive_access_all_registrants",
+}
+
+// ReservationMetricCollector implements NamespaceMetricCollector and sends the reservation
+// status of the shared namespaces supporting reservations: the reservation type, the generation,
+// the registered controllers and the number of generation changes observed by the exporter.
+type ReservationMetricCollector struct {
+	// getIdentifyData receives the devicePath and gets the Identify Namespace JSON data
+	getIdentifyData func(string) gjson.Result
+	// getData receives the devicePath and gets the reservation report JSON data
+	getData func(string) gjson.Result
+
+	typeDesc              *prometheus.Desc
+	generationDesc        *prometheus.Desc
+	generationChangesDesc *prometheus.Desc
+	registrantDesc        *prometheus.Desc
+
+	// mutex protects the fields below, since the registry may collect concurrently
+	mutex sync.Mutex
+	// lastGenerations holds the generation of the previous collection of each device
+	lastGenerations map[string]uint64
+	// generationChanges holds the number of generation changes observed for each device
+	generationChanges map[string]float64
+}
+
+// NewReservationMetricCollector initializes and returns a new ReservationMetricCollector object.
+func NewReservationMetricCollector(
+	getIdentifyData func(string) gjson.Result,
+	getData func(string) gjson.Result,
+) *ReservationMetricCollector {
+	return &ReservationMetricCollector{
+		getIdentifyData: getIdentifyData,
+		getData:         getData,
+		typeDesc: prometheus.NewDesc(
+			"nvme_reservation_type",
+			"Type of the reservation held on the namespace, 1 for the current type",
+			[]string{"device", "type"},
+			nil,
+		),
+		generationDesc: prometheus.NewDesc(
+			"nvme_reservation_generation",
+			"Generation of the namespace reservations, incremented by the controller on each registration "+
+				"and preemption",
+			[]string{"device"},
+			nil,
+		),
+		generationChangesDesc: prometheus.NewDesc(
+			"nvme_reservation_generation_changes_total",
+			"Number of changes of the reservation generation observed by the exporter",
+			[]string{"device"},
+			nil,
+		),
+		registrantDesc: prometheus.NewDesc(
+			"nvme_reservation_registrant_holder",
+			"Registered controller of the namespace reservations, 1 if it holds the reservation",
+			[]string{"device", "controller_id", "host_id"},
+			nil,
+		),
+		lastGenerations:   make(map[string]uint64),
+		generationChanges: make(map[string]float64),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (rc *ReservationMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- rc.typeDesc
+	ch <- rc.generationDesc
+	ch <- rc.generationChangesDesc
+	ch <- rc.registrantDesc
+}
+
+// CollectMetrics gets the reservation report of the shared namespaces supporting
+// reservations and sends the reservation metrics through the channel.
+func (rc *ReservationMetricCollector) CollectMetrics(ch chan<- prometheus.Metric, namespace *Namespace) error {
+	devicePath := namespace.DevicePath
+
+	identifyData := rc.getIdentifyData(devicePath)
+	if identifyData.Get("nmic").Uint()&namespaceSharedBit == 0 || identifyData.Get("rescap").Uint() == 0 {
+		return nil
+	}
+
+	jsonData := rc.getData(devicePath)
+	if !jsonData.Exists() {
+		return noDataError(devicePath, jsonData)
+	}
+
+	if reservationType := jsonData.Get("rtype").Int(); reservationType < int64(len(reservationTypes)) {
+		sendStateSet(ch, rc.typeDesc, reservationTypes, reservationTypes[reservationType], devicePath)
+	}
+
+	generation := jsonData.Get("gen").Uint()
+
+	ch <- prometheus.MustNewConstMetric(rc.generationDesc, prometheus.GaugeValue, float64(generation), devicePath)
+	ch <- prometheus.MustNewConstMetric(
+		rc.generationChangesDesc,
+		prometheus.CounterValue,
+		rc.trackGenerationChanges(devicePath, generation),
+		devicePath,
+	)
+
+	for _, registrant := range jsonData.Get("regctls").Array() {
+		ch <- prometheus.MustNewConstMetric(
+			rc.registrantDesc,
+			prometheus.GaugeValue,
+			float64(registrant.Get("rcsts").Uint()&reservationHolderBit),
+			devicePath,
+			registrant.Get("cntlid").String(),
+			reservationHostID(registrant.Get("hostid")),
+		)
+	}
+
+	return nil
+}
+
+// trackGenerationChanges updates the generation changes counter of the device with the
+// generation increments since the previous collection, and returns it. A generation lower
+// than the previous one (e.g. reset by a power loss) is counted as a single change.
+func (rc *ReservationMetricCollector) trackGenerationChanges(devicePath string, generation uint64) float64 {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	lastGeneration, found := rc.lastGenerations[devicePath]
+
+	switch {
+	case !found:
+	case generation > lastGeneration:
+		rc.generationChanges[devicePath] += float64(generation - lastGeneration)
+	case generation < lastGeneration:
+		rc.generationChanges[devicePath]++
+	}
+
+	rc.lastGenerations[devicePath] = generation
+
+	return rc.generationChanges[devicePath]
+}
+
+// reservationHostID returns the host identifier of a registrant as a hex string.
+// nvme-cli prints the 64-bit host identifiers as numbers and the extended 128-bit ones as hex strings.
+func reservationHostID(hostID gjson.Result) string {
+	if hostID.Type == gjson.Number {
+		return fmt.Sprintf("%016x", hostID.Uint())
+	}
+
+	return hostID.String()
+}
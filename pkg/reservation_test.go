@@ -0,0 +1,65 @@
+package pkg_test
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+func TestReservationGenerationChanges(t *testing.T) {
+	t.Parallel()
+
+	subsystem := &pkg.Subsystem{Name: "nvme-subsys0"}
+	controller := &pkg.Controller{Name: "nvme0", Subsystem: subsystem}
+	controller.Namespaces = []*pkg.Namespace{
+		{Name: "nvme0n1", DevicePath: "/dev/nvme0n1", NSID: 1, Controller: controller},
+	}
+	subsystem.Controllers = []*pkg.Controller{controller}
+
+	var generation atomic.Uint64
+
+	getIdentifyData := func(string) gjson.Result {
+		// A shared namespace supporting reservations
+		return gjson.Parse(`{"nmic": 1, "rescap": 255}`)
+	}
+
+	getData := func(string) gjson.Result {
+		return gjson.Parse(fmt.Sprintf(`{
+			"gen": %d,
+			"rtype": 1,
+			"regctls": [{"cntlid": 1, "rcsts": 1, "hostid": 4660}]
+		}`, generation.Load()))
+	}
+
+	collector := newTestCollector("reservation", pkg.NewReservationMetricCollector(getIdentifyData, getData),
+		subsystem)
+
+	holder := `nvme_reservation_registrant_holder{controller_id="1",device="/dev/nvme0n1",host_id="0000000000001234"}`
+
+	for _, step := range []struct {
+		generation uint64
+		changes    float64
+	}{
+		// The first collection has no previous generation
+		{generation: 5, changes: 0},
+		{generation: 5, changes: 0},
+		// Every increment between two collections is counted
+		{generation: 8, changes: 3},
+		// A generation reset is counted as a single change
+		{generation: 2, changes: 4},
+		{generation: 3, changes: 5},
+	} {
+		generation.Store(step.generation)
+
+		checkValues(t, gatherValues(t, collector), map[string]float64{
+			`nvme_reservation_generation{device="/dev/nvme0n1"}`:                  float64(step.generation),
+			`nvme_reservation_generation_changes_total{device="/dev/nvme0n1"}`:    step.changes,
+			`nvme_reservation_type{device="/dev/nvme0n1",type="write_exclusive"}`: 1,
+			holder: 1,
+		})
+	}
+}
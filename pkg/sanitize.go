@@ -0,0 +1,227 @@
+package pkg
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// sanitizeStatusInProgress is the Sanitize Operation Status of a running sanitize.
+	sanitizeStatusInProgress = 2
+	// sanitizeProgressScale is the denominator of the Sanitize Progress field.
+	sanitizeProgressScale = 65536
+	// sanitizeNoEstimate is the estimated time reported for the sanitize actions without an estimate.
+	sanitizeNoEstimate = 0xffffffff
+	// formatProgressSupportedBit is the bit of the FPI field of Identify Namespace
+	// reporting whether the format progress is supported.
+	formatProgressSupportedBit = 1 << 7
+	// formatRemainingMask selects the percentage of the format that remains to be completed in the FPI field.
+	formatRemainingMask = 0x7f
+)
+
+// sanitizeStatuses are the status label values of the Sanitize Operation Status codes.
+var sanitizeStatuses = []string{
+	"never_sanitized",
+	"completed",
+	"in_progress",
+	"failed",
+	"completed_no_deallocate",
+}
+
+// sanitizeEstimates maps the estimated time fields of the sanitize log to the action label values.
+var sanitizeEstimates = map[string]string{
+	"time_over_write":              "overwrite",
+	"time_block_erase":             "block_erase",
+	"time_crypto_erase":            "crypto_erase",
+	"time_over_write_no_dealloc":   "overwrite_no_deallocate",
+	"time_block_erase_no_dealloc":  "block_erase_no_deallocate",
+	"time_crypto_erase_no_dealloc": "crypto_erase_no_deallocate",
+}
+
+// SanitizeMetricCollector implements ControllerMetricCollector and NamespaceMetricCollector.
+// It sends the Sanitize Status log (81h) metrics of each controller: the status and progress
+// of the last sanitize operation, the estimated time of each sanitize action and whether
+// the global data was erased. It also sends the progress of the format operations running
+// on each namespace, from the FPI field of Identify Namespace.
+type SanitizeMetricCollector struct {
+	// getData receives the controller device path and gets the sanitize log JSON data
+	getData func(string) gjson.Result
+	// getIdentifyNamespaceData receives the devicePath and gets the Identify Namespace JSON data
+	getIdentifyNamespaceData func(string) gjson.Result
+
+	statusDesc         *prometheus.Desc
+	progressDesc       *prometheus.Desc
+	estimatedTimeDesc  *prometheus.Desc
+	globalErasedDesc   *prometheus.Desc
+	formatProgressDesc *prometheus.Desc
+}
+
+// NewSanitizeMetricCollector initializes and returns a new SanitizeMetricCollector object.
+func NewSanitizeMetricCollector(
+	getData func(string) gjson.Result,
+	getIdentifyNamespaceData func(string) gjson.Result,
+) *SanitizeMetricCollector {
+	return &SanitizeMetricCollector{
+		getData:                  getData,
+		getIdentifyNamespaceData: getIdentifyNamespaceData,
+		statusDesc: prometheus.NewDesc(
+			"nvme_sanitize_status",
+			"Status of the last sanitize operation of the controller, 1 for the current status",
+			[]string{"controller", "status"},
+			nil,
+		),
+		progressDesc: prometheus.NewDesc(
+			"nvme_sanitize_progress_percent",
+			"Progress of the running sanitize operation as a percentage (0-100)",
+			[]string{"controller"},
+			nil,
+		),
+		estimatedTimeDesc: prometheus.NewDesc(
+			"nvme_sanitize_estimated_time_seconds",
+			"Estimated time to complete a sanitize operation with the given action",
+			[]string{"controller", "action"},
+			nil,
+		),
+		globalErasedDesc: prometheus.NewDesc(
+			"nvme_sanitize_global_data_erased",
+			"Whether no user data has been written since the last sanitize or since manufacture (1 = erased)",
+			[]string{"controller"},
+			nil,
+		),
+		formatProgressDesc: prometheus.NewDesc(
+			"nvme_format_progress_percent",
+			"Progress of the running format operation of the namespace as a percentage (0-100)",
+			[]string{"device"},
+			nil,
+		),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (sc *SanitizeMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sc.statusDesc
+	ch <- sc.progressDesc
+	ch <- sc.estimatedTimeDesc
+	ch <- sc.globalErasedDesc
+	ch <- sc.formatProgressDesc
+}
+
+// CollectControllerMetrics gets the sanitize log of the controller and sends its metrics through the channel.
+func (sc *SanitizeMetricCollector) CollectControllerMetrics(ch chan<- prometheus.Metric, controller *Controller) error {
+	if controller.Name == "" {
+		return nil
+	}
+
+	logData := sc.getData("/dev/" + controller.Name)
+
+	jsonData := sanitizeLogEntry(logData)
+	if !jsonData.Exists() {
+		return noDataError("/dev/"+controller.Name, logData)
+	}
+
+	status, found := parseSanitizeStatus(jsonData.Get("sstat.status"))
+	if found && status < len(sanitizeStatuses) {
+		sendStateSet(ch, sc.statusDesc, sanitizeStatuses, sanitizeStatuses[status], controller.Name)
+	}
+
+	// The progress is only meaningful while a sanitize operation is running
+	if status == sanitizeStatusInProgress {
+		ch <- prometheus.MustNewConstMetric(
+			sc.progressDesc,
+			prometheus.GaugeValue,
+			jsonData.Get("sprog").Float()*100/sanitizeProgressScale,
+			controller.Name,
+		)
+	}
+
+	for field, action := range sanitizeEstimates {
+		estimate := jsonData.Get(field)
+		if !estimate.Exists() || estimate.Uint() == sanitizeNoEstimate {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			sc.estimatedTimeDesc,
+			prometheus.GaugeValue,
+			estimate.Float(),
+			controller.Name,
+			action,
+		)
+	}
+
+	if globalErased := jsonData.Get("sstat.global_erased"); globalErased.Exists() {
+		ch <- prometheus.MustNewConstMetric(
+			sc.globalErasedDesc,
+			prometheus.GaugeValue,
+			globalErased.Float(),
+			controller.Name,
+		)
+	}
+
+	return nil
+}
+
+// CollectMetrics gets the Identify Namespace data of the namespace
+// and sends the progress of the running format operation through the channel.
+func (sc *SanitizeMetricCollector) CollectMetrics(ch chan<- prometheus.Metric, namespace *Namespace) error {
+	fpi := sc.getIdentifyNamespaceData(namespace.DevicePath).Get("fpi")
+	if !fpi.Exists() || fpi.Uint()&formatProgressSupportedBit == 0 {
+		return nil
+	}
+
+	// The remaining percentage is 0 when no format operation is running
+	remaining := fpi.Uint() & formatRemainingMask
+	if remaining == 0 {
+		return nil
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		sc.formatProgressDesc,
+		prometheus.GaugeValue,
+		float64(100-remaining),
+		namespace.DevicePath,
+	)
+
+	return nil
+}
+
+// sanitizeLogEntry returns the sanitize log of the JSON output,
+// which nvme-cli nests under the device name.
+func sanitizeLogEntry(jsonData gjson.Result) gjson.Result {
+	if jsonData.Get("sprog").Exists() {
+		return jsonData
+	}
+
+	var entry gjson.Result
+
+	jsonData.ForEach(func(_, value gjson.Result) bool {
+		entry = value
+
+		return false
+	})
+
+	return entry
+}
+
+// parseSanitizeStatus returns the Sanitize Operation Status code, printed
+// by nvme-cli either as a number or as a "(<code>) <description>" string.
+func parseSanitizeStatus(status gjson.Result) (int, bool) {
+	if status.Type == gjson.Number {
+		return int(status.Int()), true
+	}
+
+	code, _, found := strings.Cut(strings.TrimPrefix(status.String(), "("), ")")
+	if !found {
+		return 0, false
+	}
+
+	value, err := strconv.Atoi(code)
+	if err != nil {
+		return 0, false
+	}
+
+	return value, true
+}
@@ -0,0 +1,88 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+func TestSanitizeMetricCollector(t *testing.T) {
+	t.Parallel()
+
+	subsystem := &pkg.Subsystem{Name: "nvme-subsys0"}
+
+	for _, name := range []string{"nvme0", "nvme1", "nvme2"} {
+		controller := &pkg.Controller{Name: name, Subsystem: subsystem}
+		controller.Namespaces = []*pkg.Namespace{
+			{Name: name + "n1", DevicePath: "/dev/" + name + "n1", NSID: 1, Controller: controller},
+		}
+		subsystem.Controllers = append(subsystem.Controllers, controller)
+	}
+
+	getData := func(devicePath string) gjson.Result {
+		switch devicePath {
+		case "/dev/nvme0":
+			// nvme-cli 2.x nests the log under the device name and prints the status as a string
+			return gjson.Parse(`{"nvme0": {
+				"sprog": 32768,
+				"sstat": {"status": "(2) Sanitize in Progress.", "global_erased": 0},
+				"time_over_write": 4294967295,
+				"time_crypto_erase": 120
+			}}`)
+		case "/dev/nvme1":
+			return gjson.Parse(`{"sprog": 65535, "sstat": {"status": 1, "global_erased": 1}}`)
+		default:
+			return pkg.SkippedResult()
+		}
+	}
+
+	getIdentifyNamespaceData := func(devicePath string) gjson.Result {
+		switch devicePath {
+		case "/dev/nvme0n1":
+			// Format progress supported, 30% remaining
+			return gjson.Parse(`{"fpi": 158}`)
+		case "/dev/nvme1n1":
+			// Format progress supported, no format running
+			return gjson.Parse(`{"fpi": 128}`)
+		default:
+			// Format progress not supported
+			return gjson.Parse(`{"fpi": 30}`)
+		}
+	}
+
+	collector := newTestCollector("sanitize", pkg.NewSanitizeMetricCollector(getData, getIdentifyNamespaceData),
+		subsystem)
+
+	values := gatherValues(t, collector)
+
+	checkValues(t, values, map[string]float64{
+		`nvme_sanitize_status{controller="nvme0",status="never_sanitized"}`:              0,
+		`nvme_sanitize_status{controller="nvme0",status="in_progress"}`:                  1,
+		`nvme_sanitize_status{controller="nvme0",status="completed"}`:                    0,
+		`nvme_sanitize_progress_percent{controller="nvme0"}`:                             50,
+		`nvme_sanitize_estimated_time_seconds{action="crypto_erase",controller="nvme0"}`: 120,
+		`nvme_sanitize_global_data_erased{controller="nvme0"}`:                           0,
+		`nvme_sanitize_status{controller="nvme1",status="completed"}`:                    1,
+		`nvme_sanitize_status{controller="nvme1",status="in_progress"}`:                  0,
+		`nvme_sanitize_global_data_erased{controller="nvme1"}`:                           1,
+		`nvme_format_progress_percent{device="/dev/nvme0n1"}`:                            70,
+		`nvme_exporter_collector_success{collector="sanitize",device="/dev/nvme2"}`:      1,
+	})
+
+	for _, unexpected := range []string{
+		// The actions without an estimate are skipped
+		`nvme_sanitize_estimated_time_seconds{action="overwrite",controller="nvme0"}`,
+		// The progress is only exported while a sanitize operation is running
+		`nvme_sanitize_progress_percent{controller="nvme1"}`,
+		// The format progress is only exported while a format operation is running, if supported
+		`nvme_format_progress_percent{device="/dev/nvme1n1"}`,
+		`nvme_format_progress_percent{device="/dev/nvme2n1"}`,
+		`nvme_sanitize_status{controller="nvme2",status="completed"}`,
+	} {
+		if _, found := values[unexpected]; found {
+			t.Errorf("%s is exported", unexpected)
+		}
+	}
+}
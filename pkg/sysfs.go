@@ -0,0 +1,220 @@
+package pkg
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg/utils"
+)
+
+// sysfsSectorSize is the unit of the block device size attribute,
+// which the kernel always reports in 512-byte sectors.
+const sysfsSectorSize = 512
+
+// namespaceBlockRegex matches both the plain namespace block devices (nvme0n1)
+// and the hidden per-path block devices of multipath namespaces (nvme0c0n1).
+var namespaceBlockRegex = regexp.MustCompile(`^nvme(\d+)(c\d+)?n(\d+)$`)
+
+// controllerNameRegex extracts the controller name from a device path.
+var controllerNameRegex = regexp.MustCompile(`nvme\d+`)
+
+// controllerDirRegex matches the controller entries of the sysfs subsystem directories.
+var controllerDirRegex = regexp.MustCompile(`^nvme\d+$`)
+
+// UsedBytesReader returns the bytes allocated to the namespace nsid of the device.
+type UsedBytesReader func(devicePath string, nsid int64) (int64, error)
+
+// GetSysfsDevices discovers the NVMe subsystems, controllers and namespaces
+// through /sys/class/nvme and returns the same topology produced by GetDevices,
+// without requiring nvme-cli.
+// UsedBytes is only available through admin commands, so it is left nil
+// unless a readUsedBytes function is given.
+func GetSysfsDevices(sysfsRoot string, readUsedBytes UsedBytesReader) []*Subsystem {
+	if !checkValidation() {
+		return []*Subsystem{}
+	}
+
+	controllerDirs, err := filepath.Glob(filepath.Join(sysfsRoot, "class", "nvme", "nvme*"))
+	if err != nil || len(controllerDirs) == 0 {
+		log.Printf("No NVMe controllers found in %s\n", filepath.Join(sysfsRoot, "class", "nvme"))
+
+		return []*Subsystem{}
+	}
+
+	subsystemNames := readSysfsSubsystemNames(sysfsRoot)
+
+	var subsystems []*Subsystem
+
+	subsystemsByKey := make(map[string]*Subsystem)
+	seen := make(map[string]bool)
+
+	for _, controllerDir := range controllerDirs {
+		controller := readSysfsController(controllerDir)
+		nqn := readSysfsStringOrEmpty(filepath.Join(controllerDir, "subsysnqn"))
+
+		// Controllers are grouped by subsystem name, falling back to the NQN
+		// on kernels without the nvme-subsystem class
+		key := subsystemNames[controller.Name]
+		if key == "" {
+			key = nqn
+		}
+
+		subsystem, found := subsystemsByKey[key]
+		if !found || key == "" {
+			subsystem = &Subsystem{
+				Name: subsystemNames[controller.Name],
+				NQN:  nqn,
+			}
+			subsystemsByKey[key] = subsystem
+			subsystems = append(subsystems, subsystem)
+		}
+
+		controller.Subsystem = subsystem
+		subsystem.Controllers = append(subsystem.Controllers, controller)
+
+		entries, err := os.ReadDir(controllerDir)
+		if err != nil {
+			log.Printf("Error reading controller directory %s: %s\n", controllerDir, err)
+
+			continue
+		}
+
+		for _, entry := range entries {
+			match := namespaceBlockRegex.FindStringSubmatch(entry.Name())
+			if match == nil {
+				continue
+			}
+
+			// Multipath paths (nvmeXcYnZ) are exposed to the host through
+			// the nvmeXnZ head device, which is shared by all the paths
+			blockName := "nvme" + match[1] + "n" + match[3]
+
+			if path := newPath(entry.Name()); path != nil {
+				controller.Paths = append(controller.Paths, path)
+			}
+
+			if seen[blockName] {
+				continue
+			}
+
+			seen[blockName] = true
+
+			namespace := readSysfsNamespace(controllerDir, controller, entry.Name(), blockName, readUsedBytes)
+			if namespace != nil {
+				controller.Namespaces = append(controller.Namespaces, namespace)
+			}
+		}
+	}
+
+	return subsystems
+}
+
+// readSysfsSubsystemNames maps the controller names to the name of
+// the subsystem they belong to, reading /sys/class/nvme-subsystem.
+func readSysfsSubsystemNames(sysfsRoot string) map[string]string {
+	names := make(map[string]string)
+
+	subsystemDirs, err := filepath.Glob(filepath.Join(sysfsRoot, "class", "nvme-subsystem", "nvme-subsys*"))
+	if err != nil {
+		return names
+	}
+
+	for _, subsystemDir := range subsystemDirs {
+		entries, err := os.ReadDir(subsystemDir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if controllerDirRegex.MatchString(entry.Name()) {
+				names[entry.Name()] = filepath.Base(subsystemDir)
+			}
+		}
+	}
+
+	return names
+}
+
+// readSysfsController reads the controller sysfs attributes.
+func readSysfsController(controllerDir string) *Controller {
+	return &Controller{
+		Name:         filepath.Base(controllerDir),
+		Transport:    readSysfsStringOrEmpty(filepath.Join(controllerDir, "transport")),
+		Address:      readSysfsStringOrEmpty(filepath.Join(controllerDir, "address")),
+		Firmware:     readSysfsStringOrEmpty(filepath.Join(controllerDir, "firmware_rev")),
+		ModelNumber:  readSysfsStringOrEmpty(filepath.Join(controllerDir, "model")),
+		SerialNumber: readSysfsStringOrEmpty(filepath.Join(controllerDir, "serial")),
+	}
+}
+
+// readSysfsNamespace reads the namespace sysfs attributes,
+// returning nil if the namespace identifier is not available.
+func readSysfsNamespace(
+	controllerDir string,
+	controller *Controller,
+	namespaceDir, blockName string,
+	readUsedBytes UsedBytesReader,
+) *Namespace {
+	namespacePath := filepath.Join(controllerDir, namespaceDir)
+
+	nsid, err := utils.ReadSysfsInt(filepath.Join(namespacePath, "nsid"))
+	if err != nil {
+		log.Printf("Error reading namespace %s: %s\n", namespacePath, err)
+
+		return nil
+	}
+
+	namespace := &Namespace{
+		Name:       blockName,
+		DevicePath: "/dev/" + blockName,
+		NSID:       nsid,
+		Controller: controller,
+	}
+
+	genericName := "ng" + controller.Name[len("nvme"):] + "n" + strconv.FormatInt(nsid, 10)
+	if _, err := os.Stat(filepath.Join(controllerDir, genericName)); err == nil {
+		namespace.GenericPath = "/dev/" + genericName
+	}
+
+	if readUsedBytes != nil {
+		usedBytes, err := readUsedBytes(namespace.DevicePath, nsid)
+		if err != nil {
+			log.Printf("Error reading used bytes of %s: %s\n", namespace.DevicePath, err)
+		} else {
+			namespace.UsedBytes = &usedBytes
+		}
+	}
+
+	// Missing attributes are left nil, so that they are skipped instead of exported as zero
+	sectors, err := utils.ReadSysfsInt(filepath.Join(namespacePath, "size"))
+	if err == nil {
+		physicalSize := sectors * sysfsSectorSize
+		namespace.PhysicalSize = &physicalSize
+	}
+
+	sectorSize, err := utils.ReadSysfsInt(filepath.Join(namespacePath, "queue", "logical_block_size"))
+	if err == nil && sectorSize > 0 {
+		namespace.SectorSize = &sectorSize
+
+		if namespace.PhysicalSize != nil {
+			maximumLBA := *namespace.PhysicalSize / sectorSize
+			namespace.MaximumLBA = &maximumLBA
+		}
+	}
+
+	return namespace
+}
+
+// readSysfsStringOrEmpty reads a sysfs attribute, returning an empty string
+// if the attribute is not available.
+func readSysfsStringOrEmpty(path string) string {
+	value, err := utils.ReadSysfsString(path)
+	if err != nil {
+		return ""
+	}
+
+	return value
+}
@@ -0,0 +1,131 @@
+package pkg
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TopologyMetricCollector implements SubsystemMetricCollector, ControllerMetricCollector
+// and NamespaceMetricCollector, and sends the info metrics describing the
+// subsystems, controllers and namespaces topology and their relationships.
+type TopologyMetricCollector struct {
+	subsystemInfoDesc        *prometheus.Desc
+	subsystemControllersDesc *prometheus.Desc
+	controllerInfoDesc       *prometheus.Desc
+	controllerNamespacesDesc *prometheus.Desc
+	namespaceInfoDesc        *prometheus.Desc
+}
+
+// NewTopologyMetricCollector initializes and returns a new TopologyMetricCollector object.
+func NewTopologyMetricCollector() *TopologyMetricCollector {
+	return &TopologyMetricCollector{
+		subsystemInfoDesc: prometheus.NewDesc(
+			"nvme_subsystem_info",
+			"NVMe subsystem information, value is always 1",
+			[]string{"subsystem", "nqn"},
+			nil,
+		),
+		subsystemControllersDesc: prometheus.NewDesc(
+			"nvme_subsystem_controllers",
+			"Number of controllers of the NVMe subsystem",
+			[]string{"subsystem"},
+			nil,
+		),
+		controllerInfoDesc: prometheus.NewDesc(
+			"nvme_controller_info",
+			"NVMe controller information, value is always 1",
+			[]string{"controller", "subsystem", "transport", "address", "model_number", "serial_number", "firmware"},
+			nil,
+		),
+		controllerNamespacesDesc: prometheus.NewDesc(
+			"nvme_controller_namespaces",
+			"Number of namespaces attached to the NVMe controller",
+			[]string{"controller", "subsystem"},
+			nil,
+		),
+		namespaceInfoDesc: prometheus.NewDesc(
+			"nvme_namespace_info",
+			"NVMe namespace information, value is always 1",
+			[]string{"device", "namespace", "nsid", "controller", "subsystem", "generic_path"},
+			nil,
+		),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (tc *TopologyMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- tc.subsystemInfoDesc
+	ch <- tc.subsystemControllersDesc
+	ch <- tc.controllerInfoDesc
+	ch <- tc.controllerNamespacesDesc
+	ch <- tc.namespaceInfoDesc
+}
+
+// CollectSubsystemMetrics sends the subsystem info and controllers count through the channel.
+func (tc *TopologyMetricCollector) CollectSubsystemMetrics(ch chan<- prometheus.Metric, subsystem *Subsystem) error {
+	// The old flat nvme-cli structure does not report subsystems
+	if subsystem.Name == "" {
+		return nil
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		tc.subsystemInfoDesc,
+		prometheus.GaugeValue,
+		1,
+		subsystem.Name,
+		subsystem.NQN,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		tc.subsystemControllersDesc,
+		prometheus.GaugeValue,
+		float64(len(subsystem.Controllers)),
+		subsystem.Name,
+	)
+
+	return nil
+}
+
+// CollectControllerMetrics sends the controller info and namespaces count through the channel.
+func (tc *TopologyMetricCollector) CollectControllerMetrics(ch chan<- prometheus.Metric, controller *Controller) error {
+	ch <- prometheus.MustNewConstMetric(
+		tc.controllerInfoDesc,
+		prometheus.GaugeValue,
+		1,
+		controller.Name,
+		controller.Subsystem.Name,
+		controller.Transport,
+		controller.Address,
+		controller.ModelNumber,
+		controller.SerialNumber,
+		controller.Firmware,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		tc.controllerNamespacesDesc,
+		prometheus.GaugeValue,
+		float64(controller.NamespaceCount()),
+		controller.Name,
+		controller.Subsystem.Name,
+	)
+
+	return nil
+}
+
+// CollectMetrics sends the namespace info through the channel.
+func (tc *TopologyMetricCollector) CollectMetrics(ch chan<- prometheus.Metric, namespace *Namespace) error {
+	ch <- prometheus.MustNewConstMetric(
+		tc.namespaceInfoDesc,
+		prometheus.GaugeValue,
+		1,
+		namespace.DevicePath,
+		namespace.Name,
+		strconv.FormatInt(namespace.NSID, 10),
+		namespace.Controller.Name,
+		namespace.Controller.Subsystem.Name,
+		namespace.GenericPath,
+	)
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+// newTestCollector returns a composite collector running the collector on the subsystems.
+func newTestCollector(
+	name string,
+	collector pkg.MetricCollector,
+	subsystems ...*pkg.Subsystem,
+) *pkg.CompositeCollector {
+	return pkg.NewCompositeCollector(
+		[]pkg.NamedMetricCollector{pkg.NewNamedMetricCollector(name, collector)},
+		func() []*pkg.Subsystem {
+			return subsystems
+		},
+	)
+}
+
+// newMultipathTestSubsystem returns a fabrics subsystem with two controllers (nvme1 and nvme2)
+// reaching two multipath namespaces: nvme1n1 through both, nvme1n2 through nvme2 only.
+func newMultipathTestSubsystem() *pkg.Subsystem {
+	subsystem := &pkg.Subsystem{Name: "nvme-subsys1", NQN: "nqn.2019-10.com.vendor:target"}
+
+	nvme1 := &pkg.Controller{
+		Name:      "nvme1",
+		Transport: "tcp",
+		Address:   "traddr=192.168.1.10,trsvcid=4420",
+		Firmware:  "6.8.0",
+		Subsystem: subsystem,
+		Paths:     []*pkg.Path{{Name: "nvme1c1n1", Namespace: "nvme1n1"}},
+	}
+	nvme2 := &pkg.Controller{
+		Name:      "nvme2",
+		Transport: "tcp",
+		Address:   "traddr=192.168.2.10,trsvcid=4420",
+		Firmware:  "6.8.0",
+		Subsystem: subsystem,
+		Paths: []*pkg.Path{
+			{Name: "nvme1c2n1", Namespace: "nvme1n1"},
+			{Name: "nvme1c2n2", Namespace: "nvme1n2"},
+		},
+	}
+
+	nvme1.Namespaces = []*pkg.Namespace{
+		{Name: "nvme1n1", DevicePath: "/dev/nvme1n1", GenericPath: "ng1n1", NSID: 1, Controller: nvme1},
+	}
+	nvme2.Namespaces = []*pkg.Namespace{
+		{Name: "nvme1n2", DevicePath: "/dev/nvme1n2", GenericPath: "ng1n2", NSID: 2, Controller: nvme2},
+	}
+	subsystem.Controllers = []*pkg.Controller{nvme1, nvme2}
+
+	return subsystem
+}
+
+func TestTopologyMetricCollector(t *testing.T) {
+	t.Parallel()
+
+	// The flat nvme-cli structure reports neither the subsystem nor the transport
+	flatSubsystem := &pkg.Subsystem{}
+	nvme0 := &pkg.Controller{Name: "nvme0", Firmware: "GDC5602Q", Subsystem: flatSubsystem}
+	nvme0.Namespaces = []*pkg.Namespace{{Name: "nvme0n1", DevicePath: "/dev/nvme0n1", NSID: 1, Controller: nvme0}}
+	flatSubsystem.Controllers = []*pkg.Controller{nvme0}
+
+	collector := newTestCollector("topology", pkg.NewTopologyMetricCollector(),
+		newMultipathTestSubsystem(), flatSubsystem)
+
+	values := gatherValues(t, collector)
+
+	checkValues(t, values, map[string]float64{
+		`nvme_subsystem_info{nqn="nqn.2019-10.com.vendor:target",subsystem="nvme-subsys1"}`: 1,
+		`nvme_subsystem_controllers{subsystem="nvme-subsys1"}`:                              2,
+		`nvme_controller_info{address="traddr=192.168.2.10,trsvcid=4420",controller="nvme2",firmware="6.8.0",` +
+			`model_number="",serial_number="",subsystem="nvme-subsys1",transport="tcp"}`: 1,
+		// The namespaces are counted once, whether attached or reached through a path
+		`nvme_controller_namespaces{controller="nvme1",subsystem="nvme-subsys1"}`: 1,
+		`nvme_controller_namespaces{controller="nvme2",subsystem="nvme-subsys1"}`: 2,
+		`nvme_controller_namespaces{controller="nvme0",subsystem=""}`:             1,
+		`nvme_namespace_info{controller="nvme2",device="/dev/nvme1n2",generic_path="ng1n2",` +
+			`namespace="nvme1n2",nsid="2",subsystem="nvme-subsys1"}`: 1,
+		`nvme_namespace_info{controller="nvme0",device="/dev/nvme0n1",generic_path="",` +
+			`namespace="nvme0n1",nsid="1",subsystem=""}`: 1,
+	})
+
+	// The anonymous subsystems of the flat structure are skipped
+	if _, found := values[`nvme_subsystem_controllers{subsystem=""}`]; found {
+		t.Error("the anonymous subsystem is exported")
+	}
+}
@@ -0,0 +1,226 @@
+package pkg
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// unsupportedInitialBackoff is the delay before retrying a command after its first failure.
+	unsupportedInitialBackoff = 5 * time.Minute
+	// unsupportedMaxBackoff caps the delay before retrying a command, doubled on each consecutive failure.
+	unsupportedMaxBackoff = 6 * time.Hour
+)
+
+// unsupportedCommand is the status of a command that failed on a device.
+type unsupportedCommand struct {
+	// failures is the number of consecutive failures of the command
+	failures int
+	// retryAt is the time after which the command is run again
+	retryAt time.Time
+}
+
+// UnsupportedCommandTracker implements ControllerMetricCollector and NamespaceMetricCollector.
+// It tracks the commands that failed on each device, so that they are skipped until their retry
+// time instead of being run (and logging a failure) on every collection. The retry delay starts
+// at 5 minutes and doubles on each consecutive failure, up to 6 hours. The tracked commands of
+// a device are cleared when its firmware revision changes, since a firmware update may add support.
+//
+// The tracker must come before the other collectors, so that it sees the firmware changes first.
+type UnsupportedCommandTracker struct {
+	// onFirmwareChange receives the path of the devices whose firmware revision changed
+	onFirmwareChange func(string)
+
+	unsupportedDesc *prometheus.Desc
+	retryDesc       *prometheus.Desc
+
+	// mutex protects the fields below, since the registry may collect concurrently
+	mutex sync.Mutex
+	// firmwares holds the firmware revision of each device path
+	firmwares map[string]string
+	// commands holds the failed commands of each device path
+	commands map[string]map[string]*unsupportedCommand
+}
+
+// NewUnsupportedCommandTracker initializes and returns a new UnsupportedCommandTracker object.
+func NewUnsupportedCommandTracker(onFirmwareChange func(string)) *UnsupportedCommandTracker {
+	labels := []string{"device", "command"}
+
+	return &UnsupportedCommandTracker{
+		onFirmwareChange: onFirmwareChange,
+		unsupportedDesc: prometheus.NewDesc(
+			"nvme_command_unsupported",
+			"Command failing on the device, skipped until its next retry",
+			labels,
+			nil,
+		),
+		retryDesc: prometheus.NewDesc(
+			"nvme_command_unsupported_retry_timestamp_seconds",
+			"Time of the next retry of the command failing on the device, in seconds since the Unix epoch",
+			labels,
+			nil,
+		),
+		firmwares: make(map[string]string),
+		commands:  make(map[string]map[string]*unsupportedCommand),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (ut *UnsupportedCommandTracker) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ut.unsupportedDesc
+	ch <- ut.retryDesc
+}
+
+// CollectControllerMetrics checks the firmware revision of the controller
+// and sends the status of its failed commands through the channel.
+func (ut *UnsupportedCommandTracker) CollectControllerMetrics(
+	ch chan<- prometheus.Metric,
+	controller *Controller,
+) error {
+	if controller.Name == "" {
+		return nil
+	}
+
+	ut.collect(ch, "/dev/"+controller.Name, controller.Firmware)
+
+	return nil
+}
+
+// CollectMetrics checks the firmware revision of the namespace controller
+// and sends the status of the failed commands of the namespace through the channel.
+func (ut *UnsupportedCommandTracker) CollectMetrics(ch chan<- prometheus.Metric, namespace *Namespace) error {
+	firmware := ""
+	if namespace.Controller != nil {
+		firmware = namespace.Controller.Firmware
+	}
+
+	ut.collect(ch, namespace.DevicePath, firmware)
+
+	return nil
+}
+
+func (ut *UnsupportedCommandTracker) collect(ch chan<- prometheus.Metric, devicePath string, firmware string) {
+	ut.mutex.Lock()
+	defer ut.mutex.Unlock()
+
+	lastFirmware, found := ut.firmwares[devicePath]
+	if found && lastFirmware != firmware {
+		log.Printf("Firmware of %s changed from %s to %s, retrying its unsupported commands\n",
+			devicePath, lastFirmware, firmware)
+		delete(ut.commands, devicePath)
+
+		if ut.onFirmwareChange != nil {
+			ut.onFirmwareChange(devicePath)
+		}
+	}
+
+	ut.firmwares[devicePath] = firmware
+
+	for command, status := range ut.commands[devicePath] {
+		ch <- prometheus.MustNewConstMetric(ut.unsupportedDesc, prometheus.GaugeValue, 1, devicePath, command)
+		ch <- prometheus.MustNewConstMetric(
+			ut.retryDesc,
+			prometheus.GaugeValue,
+			float64(status.retryAt.Unix()),
+			devicePath,
+			command,
+		)
+	}
+}
+
+// Skip returns whether the command failed on the device and its retry time has not come yet.
+func (ut *UnsupportedCommandTracker) Skip(devicePath string, command string) bool {
+	ut.mutex.Lock()
+	defer ut.mutex.Unlock()
+
+	status, found := ut.commands[devicePath][command]
+
+	return found && time.Now().Before(status.retryAt)
+}
+
+// Record updates the status of the command on the device: a failure schedules
+// the next retry with an increasing delay, a success clears the status.
+func (ut *UnsupportedCommandTracker) Record(devicePath string, command string, succeeded bool) {
+	ut.mutex.Lock()
+	defer ut.mutex.Unlock()
+
+	if succeeded {
+		delete(ut.commands[devicePath], command)
+
+		return
+	}
+
+	if ut.commands[devicePath] == nil {
+		ut.commands[devicePath] = make(map[string]*unsupportedCommand)
+	}
+
+	status, found := ut.commands[devicePath][command]
+	if !found {
+		status = &unsupportedCommand{}
+		ut.commands[devicePath][command] = status
+	}
+
+	// The delay doubles on each consecutive failure, up to the maximum
+	backoff := unsupportedInitialBackoff
+	for i := 0; i < status.failures && backoff < unsupportedMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	backoff = min(backoff, unsupportedMaxBackoff)
+
+	status.failures++
+	status.retryAt = time.Now().Add(backoff)
+
+	log.Printf("%s not supported by %s or failing, retrying in %s\n", command, devicePath, backoff)
+}
+
+// Track wraps a getter of the command, skipping the devices on which the command is unsupported
+// and recording the result of each run. An empty result is considered a failure, unless the getter
+// skipped the command itself (see SkippedResult).
+func (ut *UnsupportedCommandTracker) Track(
+	command string,
+	getData func(string) gjson.Result,
+) func(string) gjson.Result {
+	return func(devicePath string) gjson.Result {
+		return ut.track(devicePath, command, func() gjson.Result {
+			return getData(devicePath)
+		})
+	}
+}
+
+// TrackCommands is Track for the getters receiving the command, such as the vendor plugin
+// getter running several commands. Each command is tracked separately, by its arguments.
+func (ut *UnsupportedCommandTracker) TrackCommands(
+	getData func([]string, string) gjson.Result,
+) func([]string, string) gjson.Result {
+	return func(command []string, devicePath string) gjson.Result {
+		return ut.track(devicePath, strings.Join(command, " "), func() gjson.Result {
+			return getData(command, devicePath)
+		})
+	}
+}
+
+// track runs getData unless the command is skipped on the device, and records its result.
+func (ut *UnsupportedCommandTracker) track(
+	devicePath string,
+	command string,
+	getData func() gjson.Result,
+) gjson.Result {
+	if ut.Skip(devicePath, command) {
+		return SkippedResult()
+	}
+
+	jsonData := getData()
+	if IsSkipped(jsonData) {
+		return jsonData
+	}
+
+	ut.Record(devicePath, command, jsonData.Exists())
+
+	return jsonData
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ReadSysfsString reads a sysfs attribute and returns its content
+// without the trailing newline and padding.
+func ReadSysfsString(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading sysfs attribute %s: %w", path, err)
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
+
+// ReadSysfsInt reads a sysfs attribute and parses it as a base 10 integer.
+func ReadSysfsInt(path string) (int64, error) {
+	content, err := ReadSysfsString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := strconv.ParseInt(content, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing sysfs attribute %s: %w", path, err)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,297 @@
+package pkg
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// vendorMebibytes32 is the unit of the Intel and Solidigm NAND and host bytes written counters.
+const vendorMebibytes32 = 32 * 1024 * 1024
+
+// vendorField locates a common metric value in the JSON output of a vendor plugin.
+type vendorField struct {
+	// paths are the candidate gjson paths of the value, the first existing one is used
+	paths []string
+	// scale converts the value to the unit of the common metric
+	scale float64
+	// wide marks 128-bit values reported as a {"hi", "lo"} object
+	wide bool
+}
+
+// value returns the value of the field in the JSON result, combining the
+// high and low 64 bits of the wide fields.
+func (field vendorField) value(result gjson.Result) float64 {
+	if field.wide {
+		return (math.Ldexp(result.Get("hi").Float(), 64) + result.Get("lo").Float()) * field.scale
+	}
+
+	return result.Float() * field.scale
+}
+
+// vendorPlugin describes a nvme-cli vendor plugin exposing extended SMART data,
+// and how its JSON output maps onto the common vendor metrics.
+type vendorPlugin struct {
+	// name is the nvme-cli plugin name (e.g. intel)
+	name string
+	// command is the plugin command, without the device path and the output format
+	command []string
+
+	// vendorIDs are the PCI vendor IDs of the drives supported by the plugin
+	vendorIDs []string
+	// modelPrefixes are the model number prefixes of the drives supported by the plugin,
+	// used when the PCI vendor ID is not available (e.g. fabrics controllers)
+	modelPrefixes []string
+
+	// fields maps the common metric names to the plugin JSON fields
+	fields map[string]vendorField
+}
+
+// intelFields are the fields of the Intel smart-log-add output,
+// also used by the Solidigm plugin, which derives from it.
+var intelFields = map[string]vendorField{
+	"nand_bytes_written": {
+		paths: []string{"Device stats.nand_bytes_written.raw", "nand_bytes_written.raw"},
+		scale: vendorMebibytes32,
+	},
+	"host_bytes_written": {
+		paths: []string{"Device stats.host_bytes_written.raw", "host_bytes_written.raw"},
+		scale: vendorMebibytes32,
+	},
+	"wear_leveling_min": {
+		paths: []string{"Device stats.wear_leveling.min", "wear_leveling.min", "wear_leveling_count.min"},
+		scale: 1,
+	},
+	"wear_leveling_max": {
+		paths: []string{"Device stats.wear_leveling.max", "wear_leveling.max", "wear_leveling_count.max"},
+		scale: 1,
+	},
+	"wear_leveling_avg": {
+		paths: []string{"Device stats.wear_leveling.avg", "wear_leveling.avg", "wear_leveling_count.avg"},
+		scale: 1,
+	},
+	"program_fail_count": {
+		paths: []string{"Device stats.program_fail_count.raw", "program_fail_count.raw"},
+		scale: 1,
+	},
+	"erase_fail_count": {
+		paths: []string{"Device stats.erase_fail_count.raw", "erase_fail_count.raw"},
+		scale: 1,
+	},
+	"retry_count": {
+		paths: []string{
+			"Device stats.retry_buffer_overflow_count.raw",
+			"retry_buffer_overflow_count.raw",
+			"retry_buff_overflow_count.raw",
+		},
+		scale: 1,
+	},
+}
+
+// vendorPlugins are the supported nvme-cli vendor plugins.
+var vendorPlugins = []vendorPlugin{
+	{
+		name:          "intel",
+		command:       []string{"intel", "smart-log-add"},
+		vendorIDs:     []string{"0x8086"},
+		modelPrefixes: []string{"INTEL"},
+		fields:        intelFields,
+	},
+	{
+		name:          "solidigm",
+		command:       []string{"solidigm", "smart-log-add"},
+		vendorIDs:     []string{"0x025e"},
+		modelPrefixes: []string{"SOLIDIGM"},
+		fields:        intelFields,
+	},
+	{
+		name:          "wdc",
+		command:       []string{"wdc", "vs-smart-add-log"},
+		vendorIDs:     []string{"0x1b96", "0x1c58", "0x15b7"},
+		modelPrefixes: []string{"WDC", "WUS", "HGST"},
+		fields: map[string]vendorField{
+			// Physical media units are reported in bytes, as 128-bit hi/lo values
+			"nand_bytes_written": {paths: []string{"Physical media units written"}, scale: 1, wide: true},
+			"wear_leveling_min":  {paths: []string{"Min User data erase counts"}, scale: 1},
+			"wear_leveling_max":  {paths: []string{"Max User data erase counts"}, scale: 1},
+			"retry_count":        {paths: []string{"XOR recovery count"}, scale: 1},
+		},
+	},
+	{
+		name:          "micron",
+		command:       []string{"micron", "vs-smart-ext-log"},
+		vendorIDs:     []string{"0x1344"},
+		modelPrefixes: []string{"MICRON", "MTFD"},
+		fields: map[string]vendorField{
+			"nand_bytes_written": {
+				paths: []string{
+					"Extended SMART Information.Physical Media Units Written",
+					"Physical Media Units Written",
+				},
+				scale: 1,
+			},
+			"wear_leveling_min": {
+				paths: []string{
+					"Extended SMART Information.Minimum User Data Erase Count",
+					"Minimum User Data Erase Count",
+				},
+				scale: 1,
+			},
+			"wear_leveling_max": {
+				paths: []string{
+					"Extended SMART Information.Maximum User Data Erase Count",
+					"Maximum User Data Erase Count",
+				},
+				scale: 1,
+			},
+			"retry_count": {
+				paths: []string{"Extended SMART Information.XOR Recovery Count", "XOR Recovery Count"},
+				scale: 1,
+			},
+		},
+	},
+}
+
+// vendorMetric is a common metric of the vendor plugins.
+type vendorMetric struct {
+	name      string
+	help      string
+	valueType prometheus.ValueType
+}
+
+// vendorMetrics are the common metrics the vendor plugins are mapped onto.
+var vendorMetrics = []vendorMetric{
+	{"nand_bytes_written", "Bytes written to the NAND media, including the write amplification", prometheus.CounterValue},
+	{"host_bytes_written", "Bytes written to the device by the host", prometheus.CounterValue},
+	{"wear_leveling_min", "Minimum erase count of the NAND blocks", prometheus.GaugeValue},
+	{"wear_leveling_max", "Maximum erase count of the NAND blocks", prometheus.GaugeValue},
+	{"wear_leveling_avg", "Average erase count of the NAND blocks", prometheus.GaugeValue},
+	{"program_fail_count", "Number of NAND program failures", prometheus.CounterValue},
+	{"erase_fail_count", "Number of NAND erase failures", prometheus.CounterValue},
+	{"retry_count", "Number of retries reported by the vendor plugin (e.g. retry buffer overflows, XOR recoveries)",
+		prometheus.CounterValue},
+}
+
+// VendorMetricCollector implements ControllerMetricCollector and sends the extended SMART data
+// of the nvme-cli vendor plugins for each controller, mapped onto a common set of metrics.
+// The plugin is selected from the PCI vendor ID of the controller, falling back to the model number.
+type VendorMetricCollector struct {
+	sysfsRoot string
+
+	// getData receives the plugin command and the controller device path and gets the plugin JSON data
+	getData func(command []string, devicePath string) gjson.Result
+
+	descs map[string]*prometheus.Desc
+}
+
+// NewVendorMetricCollector initializes and returns a new VendorMetricCollector object.
+func NewVendorMetricCollector(
+	sysfsRoot string,
+	getData func(command []string, devicePath string) gjson.Result,
+) *VendorMetricCollector {
+	descs := make(map[string]*prometheus.Desc)
+
+	for _, metric := range vendorMetrics {
+		descs[metric.name] = prometheus.NewDesc(
+			"nvme_vendor_"+metric.name,
+			metric.help,
+			[]string{"controller", "plugin"},
+			nil,
+		)
+	}
+
+	return &VendorMetricCollector{
+		sysfsRoot: sysfsRoot,
+		getData:   getData,
+		descs:     descs,
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (vc *VendorMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range vendorMetrics {
+		ch <- vc.descs[metric.name]
+	}
+}
+
+// CollectControllerMetrics runs the vendor plugin of the controller
+// and sends the common vendor metrics through the channel.
+func (vc *VendorMetricCollector) CollectControllerMetrics(ch chan<- prometheus.Metric, controller *Controller) error {
+	if controller.Name == "" {
+		return nil
+	}
+
+	plugin := vc.findPlugin(controller)
+	if plugin == nil {
+		return nil
+	}
+
+	devicePath := "/dev/" + controller.Name
+
+	jsonData := vc.getData(plugin.command, devicePath)
+
+	// If getData returns invalid data (e.g., plugin not supported by the drive), skip this collector
+	if !jsonData.Exists() {
+		return noDataError(devicePath, jsonData)
+	}
+
+	for _, metric := range vendorMetrics {
+		field, found := plugin.fields[metric.name]
+		if !found {
+			continue
+		}
+
+		for _, path := range field.paths {
+			result := jsonData.Get(path)
+			if !result.Exists() {
+				continue
+			}
+
+			ch <- prometheus.MustNewConstMetric(
+				vc.descs[metric.name],
+				metric.valueType,
+				field.value(result),
+				controller.Name,
+				plugin.name,
+			)
+
+			break
+		}
+	}
+
+	return nil
+}
+
+// findPlugin returns the vendor plugin of the controller, or nil if none supports it.
+func (vc *VendorMetricCollector) findPlugin(controller *Controller) *vendorPlugin {
+	vendorID := strings.ToLower(
+		readSysfsStringOrEmpty(filepath.Join(vc.sysfsRoot, "class", "nvme", controller.Name, "device", "vendor")),
+	)
+
+	model := strings.ToUpper(strings.TrimSpace(controller.ModelNumber))
+
+	for i := range vendorPlugins {
+		plugin := &vendorPlugins[i]
+
+		for _, id := range plugin.vendorIDs {
+			if vendorID == id {
+				return plugin
+			}
+		}
+
+		if vendorID != "" {
+			continue
+		}
+
+		for _, prefix := range plugin.modelPrefixes {
+			if strings.HasPrefix(model, prefix) {
+				return plugin
+			}
+		}
+	}
+
+	return nil
+}
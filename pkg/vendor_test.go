@@ -0,0 +1,85 @@
+package pkg_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+func TestVendorMetricCollector(t *testing.T) {
+	t.Parallel()
+
+	sysfsRoot := t.TempDir()
+	classDir := filepath.Join(sysfsRoot, "class", "nvme")
+
+	writeSysfsAttributes(t, filepath.Join(classDir, "nvme0", "device"), map[string]string{"vendor": "0x1b96"})
+	writeSysfsAttributes(t, filepath.Join(classDir, "nvme1", "device"), map[string]string{"vendor": "0x8086"})
+	// Samsung drives have no nvme-cli plugin
+	writeSysfsAttributes(t, filepath.Join(classDir, "nvme2", "device"), map[string]string{"vendor": "0x144d"})
+
+	// The plugin outputs, keyed by command and controller device path
+	outputs := map[string]string{
+		"wdc vs-smart-add-log /dev/nvme0": `{
+			"Physical media units written": {"hi": 1, "lo": 4096},
+			"Min User data erase counts": 10,
+			"Max User data erase counts": 20,
+			"XOR recovery count": 3
+		}`,
+		"intel smart-log-add /dev/nvme1": `{"Device stats": {
+			"nand_bytes_written": {"normalized": 100, "raw": 2},
+			"wear_leveling": {"normalized": 99, "min": 1, "max": 7, "avg": 4}
+		}}`,
+	}
+
+	subsystem := &pkg.Subsystem{Name: "nvme-subsys0"}
+
+	for _, name := range []string{"nvme0", "nvme1", "nvme2"} {
+		controller := &pkg.Controller{Name: name, Subsystem: subsystem}
+		controller.Namespaces = []*pkg.Namespace{
+			{Name: name + "n1", DevicePath: "/dev/" + name + "n1", NSID: 1, Controller: controller},
+		}
+		subsystem.Controllers = append(subsystem.Controllers, controller)
+	}
+
+	collector := pkg.NewCompositeCollector(
+		[]pkg.NamedMetricCollector{
+			pkg.NewNamedMetricCollector("vendor", pkg.NewVendorMetricCollector(
+				sysfsRoot,
+				func(command []string, devicePath string) gjson.Result {
+					return gjson.Parse(outputs[strings.Join(command, " ")+" "+devicePath])
+				},
+			)),
+		},
+		func() []*pkg.Subsystem {
+			return []*pkg.Subsystem{subsystem}
+		},
+	)
+
+	values := gatherValues(t, collector)
+
+	checkValues(t, values, map[string]float64{
+		// 128-bit values combine the high and low 64 bits
+		`nvme_vendor_nand_bytes_written{controller="nvme0",plugin="wdc"}`: 1<<64 + 4096,
+		`nvme_vendor_wear_leveling_min{controller="nvme0",plugin="wdc"}`:  10,
+		`nvme_vendor_wear_leveling_max{controller="nvme0",plugin="wdc"}`:  20,
+		`nvme_vendor_retry_count{controller="nvme0",plugin="wdc"}`:        3,
+		// The Intel counters are in units of 32 MiB
+		`nvme_vendor_nand_bytes_written{controller="nvme1",plugin="intel"}`: 2 * 32 * 1024 * 1024,
+		`nvme_vendor_wear_leveling_avg{controller="nvme1",plugin="intel"}`:  4,
+	})
+
+	// Fields missing from the plugin output are skipped
+	if _, found := values[`nvme_vendor_host_bytes_written{controller="nvme1",plugin="intel"}`]; found {
+		t.Error("host_bytes_written of nvme1 is missing but exported")
+	}
+
+	for key := range values {
+		if strings.HasPrefix(key, "nvme_vendor_") && strings.Contains(key, `controller="nvme2"`) {
+			t.Errorf("%s is exported for a drive without a plugin", key)
+		}
+	}
+}
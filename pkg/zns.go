@@ -0,0 +1,165 @@
+package pkg
+
+import (
+	"path/filepath"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// znsZonedModel is the zoned model reported by the block layer for ZNS namespaces.
+const znsZonedModel = "host-managed"
+
+// znsNoLimit is the 0's based value of the MAR and MOR fields meaning no limit.
+const znsNoLimit = 0xffffffff
+
+// znsZoneStates maps the zone states printed by nvme zns report-zones to the state label values.
+var znsZoneStates = map[string]string{
+	"EMPTY":      "empty",
+	"IMP_OPENED": "implicitly_opened",
+	"EXP_OPENED": "explicitly_opened",
+	"CLOSED":     "closed",
+	"READONLY":   "read_only",
+	"FULL":       "full",
+	"OFFLINE":    "offline",
+}
+
+// ZnsMetricCollector implements NamespaceMetricCollector and sends the zone metrics
+// of the Zoned Namespace command set namespaces: the number of zones by state,
+// the open and active resources limits and the zone capacity.
+// Namespaces that the block layer does not report as host-managed zoned devices are skipped.
+type ZnsMetricCollector struct {
+	sysfsRoot string
+
+	// getIdentifyData receives the devicePath and gets the ZNS Identify Namespace JSON data
+	getIdentifyData func(string) gjson.Result
+	// getZonesData receives the devicePath and gets the zone report JSON data
+	getZonesData func(string) gjson.Result
+
+	zonesDesc        *prometheus.Desc
+	maxOpenDesc      *prometheus.Desc
+	maxActiveDesc    *prometheus.Desc
+	zoneCapacityDesc *prometheus.Desc
+}
+
+// NewZnsMetricCollector initializes and returns a new ZnsMetricCollector object.
+func NewZnsMetricCollector(
+	sysfsRoot string,
+	getIdentifyData func(string) gjson.Result,
+	getZonesData func(string) gjson.Result,
+) *ZnsMetricCollector {
+	return &ZnsMetricCollector{
+		sysfsRoot:       sysfsRoot,
+		getIdentifyData: getIdentifyData,
+		getZonesData:    getZonesData,
+		zonesDesc: prometheus.NewDesc(
+			"nvme_zns_zones",
+			"Number of zones of the zoned namespace by state",
+			[]string{"device", "state"},
+			nil,
+		),
+		maxOpenDesc: prometheus.NewDesc(
+			"nvme_zns_max_open_zones",
+			"Maximum number of zones of the zoned namespace that can be open at the same time, 0 if unlimited",
+			[]string{"device"},
+			nil,
+		),
+		maxActiveDesc: prometheus.NewDesc(
+			"nvme_zns_max_active_zones",
+			"Maximum number of zones of the zoned namespace that can be active at the same time, 0 if unlimited",
+			[]string{"device"},
+			nil,
+		),
+		zoneCapacityDesc: prometheus.NewDesc(
+			"nvme_zns_zone_capacity_bytes",
+			"Writable capacity of the largest zone of the zoned namespace in bytes",
+			[]string{"device"},
+			nil,
+		),
+	}
+}
+
+// Describe sends all prometheus.Desc pointers through the channel.
+func (zc *ZnsMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- zc.zonesDesc
+	ch <- zc.maxOpenDesc
+	ch <- zc.maxActiveDesc
+	ch <- zc.zoneCapacityDesc
+}
+
+// CollectMetrics gets the ZNS identify and zone report data of zoned namespaces
+// and sends the zone metrics through the channel.
+func (zc *ZnsMetricCollector) CollectMetrics(ch chan<- prometheus.Metric, namespace *Namespace) error {
+	devicePath := namespace.DevicePath
+
+	zoned := readSysfsStringOrEmpty(filepath.Join(zc.sysfsRoot, "block", namespace.Name, "queue", "zoned"))
+	if zoned != znsZonedModel {
+		return nil
+	}
+
+	identifyData := zc.getIdentifyData(devicePath)
+	if identifyData.Exists() {
+		zc.sendLimit(ch, zc.maxOpenDesc, identifyData.Get("mor"), devicePath)
+		zc.sendLimit(ch, zc.maxActiveDesc, identifyData.Get("mar"), devicePath)
+	}
+
+	zonesData := zc.getZonesData(devicePath)
+	if !zonesData.Exists() {
+		return noDataError(devicePath, zonesData)
+	}
+
+	counts := make(map[string]float64)
+	for _, state := range znsZoneStates {
+		counts[state] = 0
+	}
+
+	var maxCapacity uint64
+
+	for _, zone := range zonesData.Get("zone_list").Array() {
+		if state, found := znsZoneStates[zone.Get("state").String()]; found {
+			counts[state]++
+		}
+
+		// Zone capacities are printed in logical blocks, as hex strings
+		capacity, err := strconv.ParseUint(zone.Get("cap").String(), 0, 64)
+		if err == nil && capacity > maxCapacity {
+			maxCapacity = capacity
+		}
+	}
+
+	for state, count := range counts {
+		ch <- prometheus.MustNewConstMetric(zc.zonesDesc, prometheus.GaugeValue, count, devicePath, state)
+	}
+
+	if maxCapacity > 0 && namespace.SectorSize != nil && *namespace.SectorSize > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			zc.zoneCapacityDesc,
+			prometheus.GaugeValue,
+			float64(maxCapacity)*float64(*namespace.SectorSize),
+			devicePath,
+		)
+	}
+
+	return nil
+}
+
+// sendLimit converts a 0's based resources limit of the ZNS Identify Namespace
+// and sends it through the channel, reporting no limit as 0.
+func (zc *ZnsMetricCollector) sendLimit(
+	ch chan<- prometheus.Metric,
+	desc *prometheus.Desc,
+	limit gjson.Result,
+	devicePath string,
+) {
+	if !limit.Exists() {
+		return
+	}
+
+	value := 0.0
+	if limit.Uint() != znsNoLimit {
+		value = float64(limit.Uint() + 1)
+	}
+
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, devicePath)
+}
@@ -0,0 +1,70 @@
+package pkg_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/E4-Computer-Engineering/nvme_exporter/pkg"
+)
+
+func TestZnsMetricCollector(t *testing.T) {
+	t.Parallel()
+
+	sysfsRoot := t.TempDir()
+
+	writeSysfsAttributes(t, filepath.Join(sysfsRoot, "block", "nvme0n1", "queue"), map[string]string{
+		"zoned": "host-managed",
+	})
+	writeSysfsAttributes(t, filepath.Join(sysfsRoot, "block", "nvme0n2", "queue"), map[string]string{
+		"zoned": "none",
+	})
+
+	sectorSize := int64(4096)
+
+	subsystem := &pkg.Subsystem{Name: "nvme-subsys0"}
+	controller := &pkg.Controller{Name: "nvme0", Subsystem: subsystem}
+	controller.Namespaces = []*pkg.Namespace{
+		{Name: "nvme0n1", DevicePath: "/dev/nvme0n1", NSID: 1, SectorSize: &sectorSize, Controller: controller},
+		{Name: "nvme0n2", DevicePath: "/dev/nvme0n2", NSID: 2, SectorSize: &sectorSize, Controller: controller},
+	}
+	subsystem.Controllers = []*pkg.Controller{controller}
+
+	var reads []string
+
+	getIdentifyData := func(devicePath string) gjson.Result {
+		reads = append(reads, devicePath)
+
+		// 0's based limits: 14 open zones, no active zones limit
+		return gjson.Parse(`{"mor": 13, "mar": 4294967295}`)
+	}
+
+	getZonesData := func(string) gjson.Result {
+		// nvme-cli prints the zone capacities in logical blocks as hex strings
+		return gjson.Parse(`{"nr_zones": 4, "zone_list": [
+			{"slba": "0x0", "cap": "0x43500", "state": "FULL"},
+			{"slba": "0x80000", "cap": "0x43500", "state": "IMP_OPENED"},
+			{"slba": "0x100000", "cap": "0x43000", "state": "EMPTY"},
+			{"slba": "0x180000", "cap": "0x43500", "state": "EMPTY"}
+		]}`)
+	}
+
+	collector := newTestCollector("zns", pkg.NewZnsMetricCollector(sysfsRoot, getIdentifyData, getZonesData),
+		subsystem)
+
+	checkValues(t, gatherValues(t, collector), map[string]float64{
+		`nvme_zns_zones{device="/dev/nvme0n1",state="empty"}`:             2,
+		`nvme_zns_zones{device="/dev/nvme0n1",state="implicitly_opened"}`: 1,
+		`nvme_zns_zones{device="/dev/nvme0n1",state="full"}`:              1,
+		`nvme_zns_zones{device="/dev/nvme0n1",state="offline"}`:           0,
+		`nvme_zns_max_open_zones{device="/dev/nvme0n1"}`:                  14,
+		`nvme_zns_max_active_zones{device="/dev/nvme0n1"}`:                0,
+		`nvme_zns_zone_capacity_bytes{device="/dev/nvme0n1"}`:             0x43500 * 4096,
+	})
+
+	// The namespaces that are not zoned are skipped
+	if len(reads) != 1 || reads[0] != "/dev/nvme0n1" {
+		t.Errorf("the ZNS identify data was read on %v, want [/dev/nvme0n1]", reads)
+	}
+}